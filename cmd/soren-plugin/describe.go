@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// description is a plugin as its @intro, @settings and @actions replies describe it
+type description struct {
+	Raw      sdkv2.DryRunOutput
+	Intro    models.PluginIntro
+	Settings *models.Settings
+	Actions  []models.Action
+}
+
+// buildPlugin builds the plugin package into dir and returns the binary path
+func buildPlugin(pkg, dir string) (string, error) {
+	binary := filepath.Join(dir, "plugin")
+	build := exec.Command("go", "build", "-o", binary, pkg)
+	build.Stdout = os.Stderr
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return "", fmt.Errorf("build %s: %w", pkg, err)
+	}
+	return binary, nil
+}
+
+// describe builds the plugin package and runs it in dry run mode
+func describe(pkg string, timeout time.Duration) (*description, error) {
+	dir, err := os.MkdirTemp("", "soren-plugin-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	binary, err := buildPlugin(pkg, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	output := filepath.Join(dir, "description.json")
+	run := exec.Command(binary)
+	run.Env = append(os.Environ(), "SOREN_DRY_RUN=1", sdkv2.DryRunOutputEnv+"="+output)
+	// keep our stdout for the description, plugin logs go to stderr
+	run.Stdout = os.Stderr
+	run.Stderr = os.Stderr
+	if err := run.Start(); err != nil {
+		return nil, err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- run.Wait() }()
+	defer run.Process.Kill()
+
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if body, err := os.ReadFile(output); err == nil {
+			return decodeDescription(body)
+		}
+		select {
+		case err := <-exited:
+			// the plugin may have written its description right before exiting
+			if body, rerr := os.ReadFile(output); rerr == nil {
+				return decodeDescription(body)
+			}
+			if err == nil {
+				err = errors.New("plugin exited without calling Start")
+			}
+			return nil, fmt.Errorf("run %s: %w", pkg, err)
+		case <-deadline:
+			return nil, fmt.Errorf("run %s: no description after %s", pkg, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+func decodeDescription(body []byte) (*description, error) {
+	d := &description{}
+	if err := json.Unmarshal(body, &d.Raw); err != nil {
+		return nil, fmt.Errorf("invalid description: %w", err)
+	}
+	if err := json.Unmarshal(d.Raw.Intro, &d.Intro); err != nil {
+		return nil, fmt.Errorf("invalid @intro reply: %w", err)
+	}
+	if err := json.Unmarshal(d.Raw.Settings, &d.Settings); err != nil {
+		return nil, fmt.Errorf("invalid @settings reply: %w", err)
+	}
+	if err := json.Unmarshal(d.Raw.Actions, &d.Actions); err != nil {
+		return nil, fmt.Errorf("invalid @actions reply: %w", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func runGateway(args []string) error {
+	flags := flag.NewFlagSet("gateway", flag.ContinueOnError)
+	pluginID := flags.String("plugin-id", "local-plugin", "plugin ID, use bin.*.<uuid> to simulate a gateway plugin")
+	entityID := flags.String("entity", "local", "entity sending the requests")
+	port := flags.Int("port", 0, "port of the embedded NATS server, random when 0")
+	eventChannel := flags.String("event-channel", "soren.plugin.event.local", "event channel given to the plugin")
+	pkg := flags.String("run", ".", `plugin package to build and run, "" to wait for a plugin started by hand`)
+	input := flags.String("input", "", "JSON file with the action body, prompts for every form field when empty")
+	timeout := flags.Duration("timeout", 30*time.Second, "how long to wait for the plugin to start")
+	wait := flags.Duration("wait", 10*time.Minute, "how long to wait for the job to finish")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: soren-plugin gateway [flags] [method]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Runs the plugin against a local gateway on an embedded NATS server, sends the action")
+		fmt.Fprintln(os.Stderr, "request and prints its progress and the plugin events. Lists the actions without a method.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	gw, err := gateway.Start(gateway.Options{
+		Port:         *port,
+		PluginID:     *pluginID,
+		EntityID:     *entityID,
+		EventChannel: *eventChannel,
+		OnUpdate: func(u gateway.Update) {
+			fmt.Printf("[%s] job %s %s %d%% %s\n", u.Received.Format("15:04:05.000"), u.JobID, u.Command, u.Progress.Progress, compact(u.Progress))
+		},
+		OnEvent: func(e models.PluginEvent) {
+			fmt.Printf("[event] %s %s %s: %s\n", e.Event, e.Level, e.Source, e.Message)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+
+	if *pkg != "" {
+		dir, err := os.MkdirTemp("", "soren-plugin-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		binary, err := buildPlugin(*pkg, dir)
+		if err != nil {
+			return err
+		}
+		plugin := exec.Command(binary)
+		plugin.Env = append(os.Environ(), gw.Env()...)
+		plugin.Stdout = os.Stderr
+		plugin.Stderr = os.Stderr
+		if err := plugin.Start(); err != nil {
+			return err
+		}
+		defer plugin.Process.Kill()
+	} else {
+		fmt.Println("Start the plugin with:")
+		for _, env := range gw.Env() {
+			fmt.Printf("  %s\n", env)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	readyCtx, readyCancel := context.WithTimeout(ctx, *timeout)
+	defer readyCancel()
+	if *pkg == "" {
+		readyCtx = ctx
+	}
+	intro, err := gw.WaitReady(readyCtx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Plugin %s %s by %s is ready\n", intro.Name, intro.Version, intro.Author)
+
+	actions, err := gw.Actions()
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		for _, action := range actions {
+			fmt.Printf("  %-24s %s\n", action.Method, action.Title)
+		}
+		return nil
+	}
+	method := flags.Arg(0)
+
+	body := map[string]any{}
+	if *input != "" {
+		data, err := os.ReadFile(*input)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return fmt.Errorf("invalid input: %w", err)
+		}
+	} else {
+		form, err := gw.Form(method)
+		if err != nil {
+			return err
+		}
+		body, err = promptForm(form.Jsonschema, form.Jsonui, bufio.NewReader(os.Stdin), os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+
+	inv, err := gw.Invoke(method, body, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reply: %s\n", compact(inv.Reply))
+	if inv.Updates == nil {
+		return nil
+	}
+	deadline := time.After(*wait)
+	for {
+		select {
+		case _, ok := <-inv.Updates:
+			if !ok {
+				// give the plugin a moment to flush the events of the job
+				time.Sleep(500 * time.Millisecond)
+				fmt.Printf("Job %s finished, %d event(s) captured\n", inv.Reply.JobId, len(gw.Events()))
+				return nil
+			}
+		case <-deadline:
+			return fmt.Errorf("job %s did not finish within %s", inv.Reply.JobId, *wait)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func compact(v any) string {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(body)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// scaffold maps the files of a new plugin project to their template
+var scaffold = map[string]string{
+	"main.go":           "templates/main.go.tmpl",
+	"soren-plugin.yaml": "templates/soren-plugin.yaml.tmpl",
+	"go.mod":            "templates/go.mod.tmpl",
+	".env.example":      "templates/env.example.tmpl",
+	".gitignore":        "templates/gitignore.tmpl",
+}
+
+// templateFuncs are the functions the scaffold templates use
+var templateFuncs = template.FuncMap{"yaml": yamlScalar}
+
+// yamlScalar encodes a string as a YAML scalar, quoted when it would not read back as the same string
+func yamlScalar(s string) (string, error) {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+type scaffoldData struct {
+	Module string
+	Name   string
+	Author string
+	Binary string
+}
+
+func runInit(args []string) error {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	module := flags.String("module", "", "module path of the plugin, the directory name by default")
+	name := flags.String("name", "", "display name of the plugin, the directory name by default")
+	author := flags.String("author", "", "author of the plugin, the current user by default")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: soren-plugin init [-module path] [-name name] [-author author] <dir>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("a directory is required")
+	}
+	dir := flags.Arg(0)
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	data := scaffoldData{Module: *module, Name: *name, Author: *author, Binary: filepath.Base(abs)}
+	if data.Module == "" {
+		data.Module = data.Binary
+	}
+	if data.Name == "" {
+		data.Name = data.Binary
+	}
+	if data.Author == "" {
+		if u, err := user.Current(); err == nil {
+			data.Author = u.Username
+		}
+	}
+
+	for file := range scaffold {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return fmt.Errorf("%s already exists", filepath.Join(dir, file))
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for file, name := range scaffold {
+		tmpl, err := template.New(filepath.Base(name)).Funcs(templateFuncs).ParseFS(templates, name)
+		if err != nil {
+			return err
+		}
+		out := &strings.Builder{}
+		if err := tmpl.Execute(out, data); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(out.String()), 0o644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Plugin scaffolded in %s, next steps:\n\n", dir)
+	fmt.Printf("  cd %s\n", dir)
+	fmt.Println("  go get github.com/sorenhq/go-plugin-sdk@latest && go mod tidy")
+	fmt.Println("  soren-plugin lint")
+	fmt.Println("  cp .env.example .env   # then fill in the agent and plugin settings")
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+)
+
+func TestInitQuotesIntro(t *testing.T) {
+	names := []struct{ name, author string }{
+		{"Scanner", "jane"},
+		{"Scanner: the #1 plugin", "- jane"},
+		{`"quoted" 'name'`, "yes"},
+		{"[list]", "{map}"},
+	}
+	for _, n := range names {
+		dir := filepath.Join(t.TempDir(), "scanner")
+		if err := runInit([]string{"-name", n.name, "-author", n.author, dir}); err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := sdkv2.LoadManifest(filepath.Join(dir, "soren-plugin.yaml"))
+		if err != nil {
+			t.Fatalf("%q: %v", n.name, err)
+		}
+		if manifest.Intro.Name != n.name || manifest.Intro.Author != n.author {
+			t.Errorf("intro %q by %q, want %q by %q", manifest.Intro.Name, manifest.Intro.Author, n.name, n.author)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/forms"
+)
+
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	manifestPath := flags.String("f", "", "lint a manifest file instead of running the plugin")
+	timeout := flags.Duration("timeout", 30*time.Second, "how long to wait for the plugin to start")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: soren-plugin lint [-f soren-plugin.yaml] [package]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Checks the intro, settings and action forms of a manifest file, or of the plugin")
+		fmt.Fprintln(os.Stderr, "package (. by default) as it describes itself in a dry run.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var problems []forms.Problem
+	if *manifestPath != "" {
+		manifest, err := sdkv2.LoadManifest(*manifestPath)
+		if err != nil {
+			return err
+		}
+		problems = forms.Lint(manifest.Intro, manifest.Settings, manifest.Actions)
+	} else {
+		pkg := "."
+		if flags.NArg() > 0 {
+			pkg = flags.Arg(0)
+		}
+		d, err := describe(pkg, *timeout)
+		if err != nil {
+			return err
+		}
+		problems = forms.Lint(d.Intro, d.Settings, d.Actions)
+	}
+	for _, problem := range problems {
+		fmt.Println(problem.Error())
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Command soren-plugin is the tooling for plugins built with the Soren plugin SDK
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"init", "scaffold a plugin project", runInit},
+	{"manifest", "print the @intro, @settings and @actions replies of a plugin", runManifest},
+	{"lint", "check the forms and schemas of a plugin", runLint},
+	{"gateway", "invoke plugin actions on a local gateway and watch their progress", runGateway},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: soren-plugin <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `run "soren-plugin <command> -h" for the arguments of a command`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "soren-plugin "+c.name+":", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func runManifest(args []string) error {
+	flags := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	subject := flags.String("subject", "", "print only the reply of @intro, @settings or @actions")
+	timeout := flags.Duration("timeout", 30*time.Second, "how long to wait for the plugin to start")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: soren-plugin manifest [-subject @intro|@settings|@actions] [package]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Builds the plugin package (. by default), runs it with SOREN_DRY_RUN=1 and prints")
+		fmt.Fprintln(os.Stderr, "the replies it would send to @intro, @settings and @actions.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	pkg := "."
+	if flags.NArg() > 0 {
+		pkg = flags.Arg(0)
+	}
+	d, err := describe(pkg, *timeout)
+	if err != nil {
+		return err
+	}
+	switch *subject {
+	case "":
+		body, err := json.MarshalIndent(d.Raw, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(body))
+	case "@intro":
+		fmt.Println(string(d.Raw.Intro))
+	case "@settings":
+		fmt.Println(string(d.Raw.Settings))
+	case "@actions":
+		fmt.Println(string(d.Raw.Actions))
+	default:
+		return fmt.Errorf("unknown subject %s", *subject)
+	}
+	return nil
+}
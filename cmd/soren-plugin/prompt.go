@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// scopes returns the properties a JSON Forms UI schema shows, in display order
+func scopes(jsonui any) []string {
+	found := []string{}
+	switch node := jsonui.(type) {
+	case map[string]any:
+		if scope, ok := node["scope"].(string); ok {
+			found = append(found, strings.TrimPrefix(scope, "#/properties/"))
+		}
+		found = append(found, scopes(node["elements"])...)
+	case []any:
+		for _, element := range node {
+			found = append(found, scopes(element)...)
+		}
+	}
+	return found
+}
+
+// promptForm asks for every property of a JSON schema and returns the answers
+func promptForm(jsonschema, jsonui map[string]any, in *bufio.Reader, out io.Writer) (map[string]any, error) {
+	properties, _ := jsonschema["properties"].(map[string]any)
+	required := map[string]bool{}
+	if list, ok := jsonschema["required"].([]any); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	order := []string{}
+	for _, name := range scopes(jsonui) {
+		if _, ok := properties[name]; ok && !slices.Contains(order, name) {
+			order = append(order, name)
+		}
+	}
+	rest := []string{}
+	for name := range properties {
+		if !slices.Contains(order, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	order = append(order, rest...)
+
+	answers := map[string]any{}
+	for _, name := range order {
+		property, _ := properties[name].(map[string]any)
+		value, err := promptProperty(name, property, required[name], in, out)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			answers[name] = value
+		}
+	}
+	return answers, nil
+}
+
+func promptProperty(name string, property map[string]any, required bool, in *bufio.Reader, out io.Writer) (any, error) {
+	title := name
+	if t, ok := property["title"].(string); ok && t != "" {
+		title = t
+	}
+	kind, _ := property["type"].(string)
+	for {
+		label := title
+		if required {
+			label += " *"
+		}
+		if enum, ok := property["enum"].([]any); ok {
+			label += fmt.Sprintf(" %v", enum)
+		}
+		if def, ok := property["default"]; ok {
+			label += fmt.Sprintf(" (%v)", def)
+		}
+		if description, ok := property["description"].(string); ok && description != "" {
+			fmt.Fprintf(out, "  %s\n", description)
+		}
+		fmt.Fprintf(out, "%s: ", label)
+		line, err := in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if def, ok := property["default"]; ok {
+				return def, nil
+			}
+			if !required {
+				return nil, nil
+			}
+			fmt.Fprintln(out, "  a value is required")
+			continue
+		}
+		value, err := parseValue(kind, line)
+		if err != nil {
+			fmt.Fprintf(out, "  %v\n", err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// parseValue converts an answer to the JSON schema type of its property
+func parseValue(kind, line string) (any, error) {
+	switch kind {
+	case "integer":
+		return strconv.ParseInt(line, 10, 64)
+	case "number":
+		return strconv.ParseFloat(line, 64)
+	case "boolean":
+		return strconv.ParseBool(line)
+	case "array":
+		items := []any{}
+		for _, item := range strings.Split(line, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+		return items, nil
+	case "object":
+		value := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &value); err != nil {
+			return nil, fmt.Errorf("a JSON object is expected: %w", err)
+		}
+		return value, nil
+	default:
+		return line, nil
+	}
+}
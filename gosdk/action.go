@@ -3,14 +3,17 @@ package sdkv2
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/forms"
 	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
 	"github.com/sorenhq/go-plugin-sdk/logtool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Plugin struct {
@@ -18,38 +21,111 @@ type Plugin struct {
 	Intro    models.PluginIntro
 	Settings *models.Settings
 	Actions  []models.Action
+
+	handlers            map[string]HandlerFunc
+	settingsHandler     SubmitHandler
+	requirementsHandler SubmitHandler
+	accepted            *acceptedJobs
+	rateLimits          *RateLimits
+	manifest            *Manifest
+	jobEvents           *EventLogger // nil when job lifecycle events are off
+	jobTraces           sync.Map     // jobId to the trace.SpanContext of the request that started it
+	jobMethods          sync.Map     // jobId to the method it runs, until it is over
+	events              *EventLogger // of Request.Events and Job.Events, created on first use
+	eventsOnce          sync.Once
 }
 
 func NewPlugin(sdk *SorenSDK) *Plugin {
 	logtool.Init("SOREN-SDK", true)
 	newPlugin := &Plugin{
-		sdk: sdk,
+		sdk:      sdk,
+		handlers: make(map[string]HandlerFunc),
+		accepted: newAcceptedJobs(sdk.idempotency),
+	}
+	if sdk.conn != nil && sdk.eventChannel != "" && !sdk.disableJobEvents {
+		newPlugin.jobEvents = NewAsyncEventLogger(sdk, AsyncOptions{})
 	}
 	GetPluginHolder().add(sdk.pluginID, newPlugin)
 	return newPlugin
 }
+
+// eventLogger returns the asynchronous event logger that Request.Events and Job.Events bind
+func (p *Plugin) eventLogger() *EventLogger {
+	p.eventsOnce.Do(func() {
+		p.events = NewAsyncEventLogger(p.sdk, AsyncOptions{})
+	})
+	return p.events
+}
+
+// ID returns the plugin ID this plugin is registered under
+func (p *Plugin) ID() string {
+	return p.sdk.pluginID
+}
+
 func (p *Plugin) GetContext() context.Context {
 	return p.sdk.ctx
 }
-func (p *Plugin) SetSettings(settings *models.Settings, handler func(msg *nats.Msg) any) {
-	p.Settings = settings
-	if p.Settings != nil {
-		p.Settings.Handler = handler
+
+// Handle binds a handler to an action method.
+// It takes precedence over the action's RequestHandler.
+func (p *Plugin) Handle(method string, handler HandlerFunc) {
+	p.handlers[method] = handler
+}
+
+// actionHandler returns the handler bound to an action, nil if there is none
+func (p *Plugin) actionHandler(action models.Action) HandlerFunc {
+	if handler, ok := p.handlers[action.Method]; ok {
+		return handler
 	}
+	if action.RequestHandler != nil {
+		return MsgHandler(action.RequestHandler)
+	}
+	return nil
 }
+
+func (p *Plugin) SetSettings(settings *models.Settings, handler SubmitHandler) {
+	p.Settings = settings
+	p.settingsHandler = handler
+}
+
 func (p *Plugin) SetActions(actions []models.Action) {
 	p.Actions = actions
 }
-func (p *Plugin) SetIntro(intro models.PluginIntro, handler func(msg *nats.Msg) any) {
+
+func (p *Plugin) SetIntro(intro models.PluginIntro, handler SubmitHandler) {
 	p.Intro = intro
-	if p.Intro.Requirements != nil {
-		p.Intro.Requirements.Handler = handler
-	}
+	p.requirementsHandler = handler
 }
+
 func (p *Plugin) AddActions(actions []models.Action) {
 	p.Actions = append(p.Actions, actions...)
 }
+
+// LintForms checks the intro, settings and action forms, see forms.Lint.
+// All problems are returned as one forms.Problems error.
+func (p *Plugin) LintForms() error {
+	return forms.Problems(forms.Lint(p.Intro, p.Settings, p.Actions)).Err()
+}
+
 func (p *Plugin) Start() error {
+	if err := p.checkBindings(); err != nil {
+		if p.manifest != nil {
+			return fmt.Errorf("manifest bindings: %w", err)
+		}
+		p.Logger().Warnw("plugin bindings", "error", err)
+	}
+	if p.sdk.dryRun {
+		return p.writeDryRun()
+	}
+	if err := p.checkPermissionKeys(); err != nil {
+		return err
+	}
+	if err := p.LintForms(); err != nil {
+		if p.sdk.strictForms {
+			return err
+		}
+		p.Logger().Warnw("plugin forms", "error", err)
+	}
 	err := p.IntroHandler()
 	if err != nil {
 		return err
@@ -69,53 +145,139 @@ func (p *Plugin) Start() error {
 	}
 
 	<-p.sdk.ctx.Done()
-	log.Println("Plugin context done, exiting plugin:", p.Intro.Name)
+	p.Logger().Infow("plugin context done, exiting plugin", "name", p.Intro.Name)
 	return nil
 }
+
+// jobEvent sends a job lifecycle event, linked to the correlation and trace IDs of header when given
+func (p *Plugin) jobEvent(event models.JobEvent, header nats.Header) {
+	if p.jobEvents == nil {
+		return
+	}
+	logger := p.jobEvents
+	if header != nil {
+		logger = logger.WithContext(withEventScope(p.sdk.ctx, eventScope{CorrelationID: correlationID(header), TraceID: traceID(header)}))
+	}
+	if err := logger.Job(event); err != nil {
+		p.jobLogger(event.JobID).Warnw("job event error", "error", err)
+	}
+}
+
+// jobProgressEvent sends the lifecycle event of a job command
+func (p *Plugin) jobProgressEvent(jobId string, command models.Command, data models.JobProgress) {
+	status, ok := jobStatus(command, data)
+	if !ok {
+		return
+	}
+	event := models.JobEvent{JobID: jobId, Status: status, Progress: data.Progress}
+	event.EntityID, _ = GetjobsHolder().Get(jobId)
+	if status == models.JobStatusFailed {
+		event.Error = data.Details["error"]
+	}
+	if status != models.JobStatusProgress {
+		p.sdk.metrics.jobEnded(jobId, status)
+	}
+	p.jobEvent(event, nil)
+}
+
+// jobStatus is the status a job command reports, false for commands that are not lifecycle steps
+func jobStatus(command models.Command, data models.JobProgress) (models.JobStatus, bool) {
+	switch {
+	case command == models.StopCommand:
+		return models.JobStatusCancelled, true
+	case command != models.ProgressCommand:
+		return "", false
+	case data.Progress < 100:
+		return models.JobStatusProgress, true
+	case data.Details["error"] != nil:
+		return models.JobStatusFailed, true
+	default:
+		return models.JobStatusDone, true
+	}
+}
+
+// jobOver reports whether a job command ends the job
+func jobOver(command models.Command, data models.JobProgress) bool {
+	status, ok := jobStatus(command, data)
+	return ok && status != models.JobStatusProgress
+}
+
 func (p *Plugin) Done(jobId string, data map[string]any) any {
 
 	return p.Progress(jobId, models.ProgressCommand, models.JobProgress{Progress: 100, Details: data})
 
 }
+
 func (p *Plugin) Progress(jobId string, command models.Command, data models.JobProgress) any {
 
 	sub := p.sdk.makeJobSubject(jobId, string(command))
 	if entId, ok := GetjobsHolder().Get(jobId); ok {
 		sub = strings.Replace(sub, "*", entId, 1)
 	}
+	// a job that is done, failed or stopped is over, whether the final report got through or not
+	over := jobOver(command, data)
+	if over {
+		defer GetjobsHolder().Delete(jobId)
+	}
+	logger := p.jobLogger(jobId)
+	if over {
+		defer p.jobMethods.Delete(jobId)
+	}
+	p.jobProgressEvent(jobId, command, data)
+	ctx := p.sdk.ctx
+	if sc, ok := p.jobTraces.Load(jobId); ok {
+		ctx = trace.ContextWithSpanContext(ctx, sc.(trace.SpanContext))
+		if over {
+			defer p.jobTraces.Delete(jobId)
+		}
+	}
+	ctx, span := p.sdk.tracer.Start(ctx, "progress "+string(command),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", sub),
+			attribute.String("soren.job.id", jobId),
+			attribute.Int("soren.job.progress", data.Progress),
+		),
+	)
+	defer span.End()
 	dataByte, err := sonic.Marshal(data)
 	if err != nil {
-		log.Println("progress command ", command, " error:", err)
+		logger.Errorw("progress command marshal error", "command", command, "error", err)
+		spanError(span, err)
 		return err
 	}
 	for retry := range 5 {
-		msg, err := p.sdk.conn.Request(sub, dataByte, 3*time.Second)
+		req := &nats.Msg{Subject: sub, Data: dataByte}
+		p.sdk.injectTrace(ctx, req)
+		msg, err := p.sdk.requestMsg(req, 3*time.Second)
 		if err != nil {
 			if err == nats.ErrNoResponders {
+				p.sdk.metrics.progressNoResponders.add(1)
+				if retry < 4 {
+					p.sdk.metrics.progressRetries.add(1)
+				}
 				if retry > 2 {
-					log.Default().Printf("No responders for progress command:%s - retry :%d", command, retry)
+					logger.Warnw("no responders for progress command", "command", command, "retry", retry)
 				}
 				time.Sleep(time.Duration(retry+1) * time.Second)
 				continue
 
 			}
-			log.Println("progress command publish error:", err)
-			log.Println("jobid : ", jobId)
-			log.Println("subs : ", sub)
-			log.Println("body : ", string(dataByte))
+			logger.Errorw("progress command publish error", "command", command, "subject", sub, "body", string(dataByte), "error", err)
 
+			spanError(span, err)
 			return err
 		}
 		if err := p.sdk.conn.Flush(); err != nil {
-			log.Println("progress command flush error:", err)
+			logger.Errorw("progress command flush error", "command", command, "error", err)
+			spanError(span, err)
 			return err
 		}
 
-		fmt.Printf("result of %s  :  %s \n", sub, string(msg.Data))
+		logger.Debugw("progress command result", "subject", sub, "result", string(msg.Data))
 		return msg
 	}
-	if data.Progress == 100 {
-		GetjobsHolder().Delete(jobId)
-	}
+	spanError(span, nats.ErrNoResponders)
 	return nil
 }
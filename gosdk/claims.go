@@ -0,0 +1,212 @@
+package sdkv2
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+var (
+	ErrMissingToken     = errors.New("authorization token is missing")
+	ErrInvalidToken     = errors.New("authorization token is invalid")
+	ErrInvalidSignature = errors.New("authorization token signature is invalid")
+	ErrTokenExpired     = errors.New("authorization token is expired")
+	ErrPluginMismatch   = errors.New("authorization token is issued for another plugin")
+	ErrUnverifiedToken  = errors.New("authorization token is not verified")
+)
+
+// tokenLeeway tolerates clock drift between the platform and the plugin host
+const tokenLeeway = 30 * time.Second
+
+const (
+	// DefaultRolesClaim is the token claim the caller roles are read from when Config.RolesClaim is not set
+	DefaultRolesClaim = "roles"
+	// DefaultScopesClaim is the token claim the caller scopes are read from when Config.ScopesClaim is not set
+	DefaultScopesClaim = "scopes"
+)
+
+// claimNames are the token claims Claims.Roles and Claims.Scopes are read from, the defaults when empty
+type claimNames struct {
+	roles  string
+	scopes string
+}
+
+// Claims are the claims carried by a Soren Authorization token
+type Claims struct {
+	PluginID  string   `json:"pluginId"`
+	SpaceID   string   `json:"spaceId"`
+	Type      int      `json:"type"`
+	Roles     []string `json:"roles,omitempty"`  // read from Config.RolesClaim
+	Scopes    []string `json:"scopes,omitempty"` // read from Config.ScopesClaim
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	// Verified is set when the token signature was checked against a configured public key
+	Verified bool `json:"-"`
+	// Raw holds every claim of the token, including the ones above
+	Raw map[string]any `json:"-"`
+}
+
+// authToken returns the token of an Authorization header value, without the Bearer scheme
+func authToken(header string) string {
+	header = strings.TrimSpace(header)
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
+
+// parseClaims decodes the payload of a JWT without checking its signature
+func parseClaims(token string, names claimNames) (*Claims, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token payload: %w", err)
+	}
+	claims := &Claims{}
+	if err := sonic.Unmarshal(payload, claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err := sonic.Unmarshal(payload, &claims.Raw); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	claims.Roles = claimStrings(claims.Raw, names.roles, DefaultRolesClaim)
+	claims.Scopes = claimStrings(claims.Raw, names.scopes, DefaultScopesClaim)
+	return claims, nil
+}
+
+// claimStrings reads a list claim, a dotted name reaches into nested claims, e.g. realm_access.roles.
+// A string claim is split on spaces, as the OAuth scope claim is.
+func claimStrings(raw map[string]any, name, fallback string) []string {
+	if name == "" {
+		name = fallback
+	}
+	var value any = raw
+	for _, key := range strings.Split(name, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// verifyToken checks the EdDSA signature, the validity window and the plugin ID of a token
+func verifyToken(token string, keys []ed25519.PublicKey, pluginID string, names claimNames, now time.Time) (*Claims, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, ErrInvalidToken
+	}
+	headerByte, err := base64.RawURLEncoding.DecodeString(segments[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	header := struct {
+		Alg string `json:"alg"`
+	}{}
+	if err := sonic.Unmarshal(headerByte, &header); err != nil || header.Alg != "EdDSA" {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	signed := []byte(segments[0] + "." + segments[1])
+	valid := false
+	for _, key := range keys {
+		if ed25519.Verify(key, signed, signature) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+	claims, err := parseClaims(token, names)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.ExpiresAt != 0 && now.After(time.Unix(claims.ExpiresAt, 0).Add(tokenLeeway)) {
+		return nil, ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(tokenLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, ErrInvalidToken
+	}
+	if !pluginIDMatches(claims.PluginID, pluginID) {
+		return nil, ErrPluginMismatch
+	}
+	claims.Verified = true
+	return claims, nil
+}
+
+// pluginIDMatches compares the pluginId claim with the configured plugin ID.
+// Tokens of bin.* plugins only carry the uuid part of the plugin ID.
+func pluginIDMatches(claimed, pluginID string) bool {
+	if claimed == "" {
+		return false
+	}
+	if claimed == pluginID {
+		return true
+	}
+	if strings.HasPrefix(pluginID, "bin.*.") {
+		return claimed == strings.TrimPrefix(pluginID, "bin.*.")
+	}
+	return false
+}
+
+// parsePublicKey accepts a PEM encoded PKIX key or a base64 encoded raw ed25519 key
+func parsePublicKey(key string) (ed25519.PublicKey, error) {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			return nil, fmt.Errorf("invalid PEM public key")
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is not an ed25519 key")
+		}
+		return edKey, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		raw, err = base64.RawURLEncoding.DecodeString(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key size %d", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
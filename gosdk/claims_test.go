@@ -0,0 +1,90 @@
+package sdkv2
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func signToken(t *testing.T, key ed25519.PrivateKey, claims string) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(claims))
+	signature := ed25519.Sign(key, []byte(header+"."+payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyToken(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	now := time.Unix(1700000000, 0)
+	pluginID := "bin.*.a2d975fe-a4ba-4028-7532-b1cae2676f1e"
+	keys := []ed25519.PublicKey{publicKey}
+
+	cases := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", signToken(t, privateKey, `{"pluginId":"a2d975fe-a4ba-4028-7532-b1cae2676f1e","spaceId":"s1","type":10,"exp":1700000100}`), nil},
+		{"missing", "", ErrMissingToken},
+		{"malformed", "abc", ErrInvalidToken},
+		{"wrong key", signToken(t, otherKey, `{"pluginId":"a2d975fe-a4ba-4028-7532-b1cae2676f1e"}`), ErrInvalidSignature},
+		{"expired", signToken(t, privateKey, `{"pluginId":"a2d975fe-a4ba-4028-7532-b1cae2676f1e","exp":1699990000}`), ErrTokenExpired},
+		{"other plugin", signToken(t, privateKey, `{"pluginId":"another"}`), ErrPluginMismatch},
+	}
+	for _, c := range cases {
+		claims, err := verifyToken(c.token, keys, pluginID, claimNames{}, now)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: got error %v, want %v", c.name, err, c.err)
+			continue
+		}
+		if c.err == nil && (!claims.Verified || claims.SpaceID != "s1" || claims.Type != 10) {
+			t.Errorf("%s: unexpected claims %+v", c.name, claims)
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+	parsed, err := parsePublicKey(base64.StdEncoding.EncodeToString(publicKey))
+	if err != nil || !parsed.Equal(publicKey) {
+		t.Fatalf("raw key not parsed: %v", err)
+	}
+	if _, err := parsePublicKey("c2hvcnQ="); err == nil {
+		t.Fatal("short key accepted")
+	}
+}
+
+func TestClaimNames(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(nil)
+	token := signToken(t, privateKey, `{"pluginId":"p","roles":["viewer"],"scopes":["read"],`+
+		`"realm_access":{"roles":["admin","owner"]},"scope":"repo:read repo:write","groups":"ops"}`)
+	tests := []struct {
+		name   string
+		names  claimNames
+		roles  []string
+		scopes []string
+	}{
+		{"defaults", claimNames{}, []string{"viewer"}, []string{"read"}},
+		{"nested roles, space separated scopes", claimNames{roles: "realm_access.roles", scopes: "scope"}, []string{"admin", "owner"}, []string{"repo:read", "repo:write"}},
+		{"single string", claimNames{roles: "groups"}, []string{"ops"}, []string{"read"}},
+		{"missing", claimNames{roles: "permissions", scopes: "realm_access.scopes"}, nil, nil},
+		{"not an object", claimNames{roles: "groups.admin"}, nil, []string{"read"}},
+	}
+	for _, tt := range tests {
+		claims, err := parseClaims(token, tt.names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(claims.Roles) != fmt.Sprint(tt.roles) || fmt.Sprint(claims.Scopes) != fmt.Sprint(tt.scopes) {
+			t.Errorf("%s: roles %v scopes %v, want %v %v", tt.name, claims.Roles, claims.Scopes, tt.roles, tt.scopes)
+		}
+	}
+}
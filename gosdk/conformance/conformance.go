@@ -0,0 +1,234 @@
+// Package conformance checks that a plugin follows the Sorenv2 protocol. It runs the
+// plugin against a local gateway, exercises every declared action and reports protocol
+// violations as test failures.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/forms"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// Suite configures a conformance run
+type Suite struct {
+	// PluginID the plugin runs under, bin.*.<uuid> to check a gateway plugin
+	PluginID string
+	// Plugin sets up the plugin under test on sdk, without starting it
+	Plugin func(sdk *sdkv2.SorenSDK) (*sdkv2.Plugin, error)
+	// Inputs overrides the schema generated body of an action
+	Inputs map[string]map[string]any
+	// Skip lists actions that must not be invoked, e.g. destructive ones
+	Skip []string
+	// StartTimeout bounds how long the plugin takes to answer @intro, 10 seconds by default
+	StartTimeout time.Duration
+	// JobTimeout bounds how long an accepted job takes to finish, 30 seconds by default
+	JobTimeout time.Duration
+}
+
+// Run checks the plugin of the suite
+func Run(t *testing.T, suite Suite) {
+	t.Helper()
+	if suite.PluginID == "" {
+		suite.PluginID = "conformance-plugin"
+	}
+	if suite.StartTimeout == 0 {
+		suite.StartTimeout = 10 * time.Second
+	}
+	if suite.JobTimeout == 0 {
+		suite.JobTimeout = 30 * time.Second
+	}
+	eventChannel := "soren.plugin.event.conformance"
+	gw, err := gateway.Start(gateway.Options{
+		PluginID:     suite.PluginID,
+		EventChannel: eventChannel,
+	})
+	if err != nil {
+		t.Fatalf("start gateway: %v", err)
+	}
+	t.Cleanup(gw.Close)
+
+	sdk, err := sdkv2.New(&sdkv2.Config{
+		AgentURI:     gw.URL(),
+		PluginID:     suite.PluginID,
+		EventChannel: eventChannel,
+	})
+	if err != nil {
+		t.Fatalf("create SDK: %v", err)
+	}
+	t.Cleanup(func() { sdk.Close() })
+	plugin, err := suite.Plugin(sdk)
+	if err != nil {
+		t.Fatalf("set up plugin: %v", err)
+	}
+	started := make(chan error, 1)
+	go func() { started <- plugin.Start() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), suite.StartTimeout)
+	defer cancel()
+	readyCtx, readyCancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case err := <-started:
+			if err != nil {
+				t.Errorf("plugin Start failed: %v", err)
+			}
+			readyCancel()
+		case <-readyCtx.Done():
+		}
+	}()
+	intro, err := gw.WaitReady(readyCtx)
+	readyCancel()
+	if err != nil {
+		t.Fatalf("protocol violation: @intro is not answered: %v", err)
+	}
+
+	t.Run("intro", func(t *testing.T) {
+		checkIntro(t, intro)
+	})
+	t.Run("settings", func(t *testing.T) {
+		checkSettings(t, gw)
+	})
+	actions, err := gw.Actions()
+	if err != nil {
+		t.Fatalf("protocol violation: @actions is not answered with a list of actions: %v", err)
+	}
+	for _, action := range actions {
+		t.Run("action/"+action.Method, func(t *testing.T) {
+			checkAction(t, gw, suite, action)
+		})
+	}
+	t.Run("events", func(t *testing.T) {
+		for _, event := range gw.Events() {
+			if event.Event == "" || event.Timestamp == 0 {
+				t.Errorf("protocol violation: event without type or timestamp: %+v", event)
+			}
+		}
+	})
+}
+
+func checkIntro(t *testing.T, intro models.PluginIntro) {
+	if intro.Name == "" {
+		t.Error("protocol violation: @intro has no name")
+	}
+	if intro.Version == "" {
+		t.Error("protocol violation: @intro has no version")
+	}
+	if intro.Requirements != nil {
+		for _, problem := range forms.LintForm("requirements", intro.Requirements.Jsonui, intro.Requirements.Jsonschema) {
+			t.Errorf("invalid form: %v", problem)
+		}
+	}
+}
+
+func checkSettings(t *testing.T, gw *gateway.Gateway) {
+	settings, err := gw.Settings()
+	if err != nil {
+		t.Fatalf("protocol violation: @settings is not answered with settings or an empty reply: %v", err)
+	}
+	if settings == nil {
+		return
+	}
+	for _, problem := range forms.LintForm("settings", settings.Jsonui, settings.Jsonschema) {
+		t.Errorf("invalid form: %v", problem)
+	}
+}
+
+func checkAction(t *testing.T, gw *gateway.Gateway, suite Suite, action models.Action) {
+	if action.Method == "" {
+		t.Fatal("protocol violation: action without method")
+	}
+	form, err := gw.Form(action.Method)
+	if err != nil {
+		t.Fatalf("protocol violation: @form is not answered: %v", err)
+	}
+	if !sameJSON(form, action.Form) {
+		t.Error("protocol violation: @form differs from the form listed in @actions")
+	}
+	for _, problem := range forms.LintForm("action "+action.Method, form.Jsonui, form.Jsonschema) {
+		t.Errorf("invalid form: %v", problem)
+	}
+	if slices.Contains(suite.Skip, action.Method) {
+		t.Skip("action is skipped")
+	}
+
+	body, ok := suite.Inputs[action.Method]
+	if !ok {
+		body = forms.SampleObject(form.Jsonschema)
+	}
+	inv, err := gw.Invoke(action.Method, body, nil)
+	if err != nil {
+		t.Fatalf("protocol violation: request is not answered with a JobBodyContent: %v", err)
+	}
+	rejection, rejected := inv.Reply.Details["error"]
+	switch {
+	case inv.Reply.JobId == "" && !rejected:
+		t.Fatalf("protocol violation: reply has neither a jobId nor details.error: %+v", inv.Reply)
+	case inv.Reply.JobId != "" && rejected:
+		t.Fatalf("protocol violation: reply has both a jobId and details.error: %+v", inv.Reply)
+	case rejected:
+		if problem := rejectionProblem(rejection); problem != "" {
+			t.Errorf("protocol violation: %s: %v", problem, rejection)
+		}
+		t.Logf("request rejected: %v", rejection)
+		return
+	}
+
+	last := -1
+	timeout := time.After(suite.JobTimeout)
+	for {
+		select {
+		case update, ok := <-inv.Updates:
+			if !ok {
+				return
+			}
+			if update.Command != string(models.ProgressCommand) {
+				continue
+			}
+			progress := update.Progress.Progress
+			if progress < 0 || progress > 100 {
+				t.Errorf("protocol violation: progress %d is out of 0-100", progress)
+			}
+			if progress < last {
+				t.Errorf("protocol violation: progress went back from %d to %d", last, progress)
+			}
+			last = progress
+		case <-timeout:
+			t.Fatalf("protocol violation: job %s did not finish within %s, last progress %d", inv.Reply.JobId, suite.JobTimeout, last)
+		}
+	}
+}
+
+// rejectionProblem returns what is wrong with the details.error of a rejection, empty when nothing is.
+// The error is an object with a code, or without one as the deprecated RejectWithBody sends it.
+func rejectionProblem(rejection any) string {
+	m, ok := rejection.(map[string]any)
+	if !ok {
+		return "details.error is not an object"
+	}
+	if code, ok := m["code"]; ok {
+		if s, _ := code.(string); s == "" {
+			return "details.error.code is not a string"
+		}
+	}
+	return ""
+}
+
+func sameJSON(a, b any) bool {
+	aByte, aErr := json.Marshal(a)
+	bByte, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+	var aValue, bValue any
+	json.Unmarshal(aByte, &aValue)
+	json.Unmarshal(bByte, &bValue)
+	return reflect.DeepEqual(aValue, bValue)
+}
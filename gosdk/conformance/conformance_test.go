@@ -0,0 +1,64 @@
+package conformance
+
+import (
+	"testing"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestRun(t *testing.T) {
+	Run(t, Suite{
+		PluginID: "bin.*.a2d975fe-a4ba-4028-7532-b1cae2676f1e",
+		Plugin: func(sdk *sdkv2.SorenSDK) (*sdkv2.Plugin, error) {
+			plugin := sdkv2.NewPlugin(sdk)
+			plugin.SetIntro(models.PluginIntro{Name: "Conformance", Version: "1.0.0", Author: "Soren Team"}, nil)
+			form := models.ActionFormBuilder{
+				Jsonui: map[string]any{"type": "Control", "scope": "#/properties/name"},
+				Jsonschema: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"name": map[string]any{"type": "string", "minLength": 3}},
+					"required":   []any{"name"},
+				},
+			}
+			plugin.AddActions([]models.Action{
+				{Method: "greet", Title: "Greet", Form: form},
+				{Method: "refuse", Title: "Refuse", Form: form},
+				{Method: "legacy", Title: "Legacy", Form: form},
+			})
+			plugin.Handle("greet", func(req *sdkv2.Request) {
+				req.Accept()
+				req.Job().Progress(models.JobProgress{Progress: 50})
+				req.Job().Done(map[string]any{"greeting": "Hello " + req.Body["name"].(string)})
+			})
+			plugin.Handle("refuse", func(req *sdkv2.Request) {
+				req.Fail(sdkv2.NewError(sdkv2.CodeForbidden, "not today"))
+			})
+			plugin.Handle("legacy", func(req *sdkv2.Request) {
+				sdkv2.RejectWithBody(req.Msg, map[string]any{"reason": "not today"})
+			})
+			return plugin, nil
+		},
+	})
+}
+
+func TestRejectionProblem(t *testing.T) {
+	tests := []struct {
+		name      string
+		rejection any
+		ok        bool
+	}{
+		{"error", map[string]any{"code": "forbidden", "message": "not today"}, true},
+		{"reject with body", map[string]any{"reason": "not today"}, true},
+		{"empty code", map[string]any{"code": ""}, false},
+		{"number code", map[string]any{"code": 403.0}, false},
+		{"string", "not today", false},
+		{"array", []any{"not", "today"}, false},
+		{"null", nil, false},
+	}
+	for _, tt := range tests {
+		if problem := rejectionProblem(tt.rejection); (problem == "") != tt.ok {
+			t.Errorf("%s: problem %q", tt.name, problem)
+		}
+	}
+}
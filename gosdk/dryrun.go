@@ -0,0 +1,60 @@
+package sdkv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DryRunOutputEnv names the file a dry run writes the plugin description to
+const DryRunOutputEnv = "SOREN_DRY_RUN_OUTPUT"
+
+// DryRunOutput is what a dry run writes, each field holds the exact reply of its subject
+type DryRunOutput struct {
+	Intro    json.RawMessage `json:"@intro"`
+	Settings json.RawMessage `json:"@settings"`
+	Actions  json.RawMessage `json:"@actions"`
+}
+
+// writeDryRun writes the @intro, @settings and @actions replies instead of serving them
+func (p *Plugin) writeDryRun() error {
+	output := DryRunOutput{}
+	var err error
+	if output.Intro, err = p.introReply(); err != nil {
+		return fmt.Errorf("intro: %w", err)
+	}
+	if output.Settings, err = p.settingsReply(); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	if output.Settings == nil {
+		output.Settings = json.RawMessage("null")
+	}
+	if output.Actions, err = p.actionsReply(); err != nil {
+		return fmt.Errorf("actions: %w", err)
+	}
+	body, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	path := os.Getenv(DryRunOutputEnv)
+	if path == "" {
+		_, err = fmt.Println(string(body))
+		return err
+	}
+	// write then rename so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".dry-run-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
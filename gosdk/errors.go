@@ -0,0 +1,95 @@
+package sdkv2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode classifies why a request or job failed
+type ErrorCode string
+
+const (
+	CodeInvalidInput ErrorCode = "invalid_input"
+	CodeUnauthorized ErrorCode = "unauthorized"
+	CodeForbidden    ErrorCode = "forbidden"
+	CodeRateLimited  ErrorCode = "rate_limited"
+	CodeUnavailable  ErrorCode = "unavailable"
+	CodeInternal     ErrorCode = "internal"
+)
+
+// Sentinels to match errors by code, e.g. errors.Is(err, sdkv2.ErrForbidden)
+var (
+	ErrInvalidInput = &Error{Code: CodeInvalidInput}
+	ErrUnauthorized = &Error{Code: CodeUnauthorized}
+	ErrForbidden    = &Error{Code: CodeForbidden}
+	ErrRateLimited  = &Error{Code: CodeRateLimited}
+	ErrUnavailable  = &Error{Code: CodeUnavailable}
+	ErrInternal     = &Error{Code: CodeInternal}
+)
+
+// Error is the error reported to the platform when a request is rejected or a job fails
+type Error struct {
+	Code      ErrorCode      `json:"code"`
+	Message   string         `json:"message"`
+	Retryable bool           `json:"retryable"`
+	Details   map[string]any `json:"details,omitempty"`
+	cause     error
+}
+
+// NewError creates an error, rate_limited and unavailable errors are retryable
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{
+		Code:      code,
+		Message:   message,
+		Retryable: code == CodeRateLimited || code == CodeUnavailable,
+	}
+}
+
+// WrapError creates an error with code caused by err
+func WrapError(code ErrorCode, err error) *Error {
+	e := NewError(code, err.Error())
+	e.cause = err
+	return e
+}
+
+// WithDetails returns a copy of e with details merged in
+func (e *Error) WithDetails(details map[string]any) *Error {
+	c := *e
+	c.Details = make(map[string]any, len(e.Details)+len(details))
+	for k, v := range e.Details {
+		c.Details[k] = v
+	}
+	for k, v := range details {
+		c.Details[k] = v
+	}
+	return &c
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return string(e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches any *Error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// AsError returns err as an *Error, errors that are not one are reported as internal
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return WrapError(CodeInternal, err)
+}
@@ -0,0 +1,134 @@
+package sdkv2_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		is        error // sentinel the error matches
+		isNot     error // sentinel it does not match
+		code      sdkv2.ErrorCode
+		message   string
+		retryable bool
+		cause     error // matched with errors.Is through the chain
+	}{
+		{
+			name:    "new error",
+			err:     sdkv2.NewError(sdkv2.CodeForbidden, "not an owner"),
+			is:      sdkv2.ErrForbidden,
+			isNot:   sdkv2.ErrUnauthorized,
+			code:    sdkv2.CodeForbidden,
+			message: "not an owner",
+		},
+		{
+			name:      "retryable code",
+			err:       sdkv2.NewError(sdkv2.CodeRateLimited, "slow down"),
+			is:        sdkv2.ErrRateLimited,
+			isNot:     sdkv2.ErrInternal,
+			code:      sdkv2.CodeRateLimited,
+			message:   "slow down",
+			retryable: true,
+		},
+		{
+			name:      "wrapped cause",
+			err:       sdkv2.WrapError(sdkv2.CodeUnavailable, io.ErrUnexpectedEOF),
+			is:        sdkv2.ErrUnavailable,
+			isNot:     sdkv2.ErrInvalidInput,
+			code:      sdkv2.CodeUnavailable,
+			message:   io.ErrUnexpectedEOF.Error(),
+			retryable: true,
+			cause:     io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "wrapped by fmt",
+			err:     fmt.Errorf("loading repo: %w", sdkv2.NewError(sdkv2.CodeInvalidInput, "no repo")),
+			is:      sdkv2.ErrInvalidInput,
+			isNot:   sdkv2.ErrForbidden,
+			code:    sdkv2.CodeInvalidInput,
+			message: "no repo",
+		},
+		{
+			name:    "plain error",
+			err:     io.EOF,
+			isNot:   sdkv2.ErrInternal,
+			code:    sdkv2.CodeInternal,
+			message: io.EOF.Error(),
+			cause:   io.EOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.is != nil && !errors.Is(tt.err, tt.is) {
+				t.Errorf("errors.Is(%v, %v) = false", tt.err, tt.is)
+			}
+			if errors.Is(tt.err, tt.isNot) {
+				t.Errorf("errors.Is(%v, %v) = true", tt.err, tt.isNot)
+			}
+			var e *sdkv2.Error
+			if errors.As(tt.err, &e) != (tt.is != nil) {
+				t.Errorf("errors.As(%v) = %v", tt.err, e)
+			}
+			got := sdkv2.AsError(tt.err)
+			if got.Code != tt.code || got.Message != tt.message || got.Retryable != tt.retryable {
+				t.Errorf("AsError = %+v", got)
+			}
+			if !errors.Is(got, &sdkv2.Error{Code: tt.code}) {
+				t.Errorf("AsError(%v) does not match its code", tt.err)
+			}
+			if tt.cause != nil && !errors.Is(got, tt.cause) {
+				t.Errorf("AsError(%v) lost the cause", tt.err)
+			}
+		})
+	}
+	if sdkv2.AsError(nil) != nil {
+		t.Error("AsError(nil) is not nil")
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want map[string]any
+	}{
+		{
+			name: "with details",
+			err:  sdkv2.NewError(sdkv2.CodeRateLimited, "slow down").WithDetails(map[string]any{"retryAfter": 3}),
+			want: map[string]any{"code": "rate_limited", "message": "slow down", "retryable": true, "details": map[string]any{"retryAfter": float64(3)}},
+		},
+		{
+			name: "plain error",
+			err:  io.EOF,
+			want: map[string]any{"code": "internal", "message": "EOF", "retryable": false},
+		},
+	}
+	for _, tt := range tests {
+		// the error goes out as details.error of the reply
+		body, err := json.Marshal(models.JobBodyContent{Details: map[string]any{"error": sdkv2.AsError(tt.err)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply := struct {
+			Details struct {
+				Error map[string]any `json:"error"`
+			} `json:"details"`
+		}{}
+		if err := json.Unmarshal(body, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(reply.Details.Error, tt.want) {
+			t.Errorf("%s: error reply %v, want %v", tt.name, reply.Details.Error, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package sdkv2
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// ErrEventLoggerClosed is returned when logging to a closed asynchronous EventLogger
+var ErrEventLoggerClosed = errors.New("event logger closed")
+
+// OverflowPolicy is what an asynchronous EventLogger does with events while its buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest makes room by dropping the oldest buffered event
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowBlock makes Log wait until the buffer has room
+	OverflowBlock
+	// OverflowSample keeps one in SampleEvery events, dropping the oldest buffered one for it, and drops the others
+	OverflowSample
+)
+
+// AsyncOptions configures an asynchronous EventLogger, zero values take the defaults
+type AsyncOptions struct {
+	BufferSize    int           // events buffered before the overflow policy applies, 1024 by default
+	BatchSize     int           // events sent per request and buffered events that trigger a flush, 100 by default
+	FlushInterval time.Duration // longest time an event waits in the buffer, 1s by default
+	Overflow      OverflowPolicy
+	SampleEvery   int // for OverflowSample, 10 by default
+}
+
+// EventStats are the counters of an asynchronous EventLogger
+type EventStats struct {
+	Sent     uint64 // events the platform accepted
+	Dropped  uint64 // events dropped by the overflow policy or logged after Close
+	Failed   uint64 // events in batches that could not be sent
+	Buffered int    // events waiting to be sent
+}
+
+// NewAsyncEventLogger creates an event logger whose Log and EmitEvent return immediately.
+// Events are buffered and sent in batches with SendMultipleEvents, by size or interval.
+// Close, or closing the SDK, sends what is left.
+func NewAsyncEventLogger(sdk *SorenSDK, opts AsyncOptions) *EventLogger {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.BatchSize > opts.BufferSize {
+		opts.BatchSize = opts.BufferSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.SampleEvery <= 0 {
+		opts.SampleEvery = 10
+	}
+	e := &EventLogger{sdk: sdk}
+	q := &eventQueue{
+		logger:   e,
+		opts:     opts,
+		buffer:   make([]models.PluginEvent, 0, opts.BufferSize),
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	q.space = sync.NewCond(&q.mutex)
+	e.queue = q
+	sdk.addEventQueue(q)
+	go q.run()
+	return e
+}
+
+// Flush sends the buffered events now, it does nothing for a synchronous logger
+func (e *EventLogger) Flush() {
+	if e.queue != nil {
+		e.queue.send()
+	}
+}
+
+// Close sends the buffered events and stops the logger, further events are dropped.
+// It does nothing for a synchronous logger.
+func (e *EventLogger) Close() error {
+	if e.queue == nil {
+		return nil
+	}
+	e.queue.close()
+	return nil
+}
+
+// Stats returns the counters of an asynchronous logger, zero for a synchronous one
+func (e *EventLogger) Stats() EventStats {
+	if e.queue == nil {
+		return EventStats{}
+	}
+	return e.queue.stats()
+}
+
+// eventQueue buffers the events of an asynchronous EventLogger
+type eventQueue struct {
+	logger *EventLogger
+	opts   AsyncOptions
+
+	mutex      sync.Mutex
+	space      *sync.Cond // signaled when events leave the buffer or the queue closes
+	buffer     []models.PluginEvent
+	closed     bool
+	overflowed uint64
+
+	// sending keeps batches in order when Flush and the worker send at once
+	sending sync.Mutex
+
+	flush     chan struct{}
+	done      chan struct{}
+	finished  chan struct{}
+	closeOnce sync.Once
+
+	sent, dropped, failed atomic.Uint64
+}
+
+func (q *eventQueue) push(event models.PluginEvent) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for !q.closed && len(q.buffer) >= q.opts.BufferSize {
+		if q.opts.Overflow == OverflowBlock {
+			q.space.Wait()
+			continue
+		}
+		if q.opts.Overflow == OverflowSample {
+			q.overflowed++
+			if q.overflowed%uint64(q.opts.SampleEvery) != 0 {
+				q.dropped.Add(1)
+				return nil
+			}
+		}
+		q.buffer = append(q.buffer[:0], q.buffer[1:]...)
+		q.dropped.Add(1)
+	}
+	if q.closed {
+		q.dropped.Add(1)
+		return ErrEventLoggerClosed
+	}
+	q.buffer = append(q.buffer, event)
+	if len(q.buffer) >= q.opts.BatchSize {
+		select {
+		case q.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (q *eventQueue) run() {
+	defer close(q.finished)
+	ticker := time.NewTicker(q.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-q.flush:
+		case <-q.done:
+			q.send()
+			return
+		}
+		q.send()
+	}
+}
+
+// send sends the buffered events in batches
+func (q *eventQueue) send() {
+	q.sending.Lock()
+	defer q.sending.Unlock()
+	for {
+		q.mutex.Lock()
+		n := min(len(q.buffer), q.opts.BatchSize)
+		batch := append([]models.PluginEvent(nil), q.buffer[:n]...)
+		q.buffer = append(q.buffer[:0], q.buffer[n:]...)
+		q.space.Broadcast()
+		q.mutex.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := q.logger.SendMultipleEvents(batch...); err != nil {
+			failed := n
+			var perr *PublishError
+			if errors.As(err, &perr) {
+				// the events of the other subjects were sent
+				failed = perr.events()
+			}
+			q.failed.Add(uint64(failed))
+			q.sent.Add(uint64(n - failed))
+			eventsLogger().Errorw("event batch error", "error", err, "events", failed)
+			continue
+		}
+		q.sent.Add(uint64(n))
+	}
+}
+
+func (q *eventQueue) close() {
+	q.closeOnce.Do(func() {
+		q.mutex.Lock()
+		q.closed = true
+		q.space.Broadcast()
+		q.mutex.Unlock()
+		close(q.done)
+	})
+	<-q.finished
+}
+
+func (q *eventQueue) stats() EventStats {
+	q.mutex.Lock()
+	buffered := len(q.buffer)
+	q.mutex.Unlock()
+	return EventStats{
+		Sent:     q.sent.Load(),
+		Dropped:  q.dropped.Load(),
+		Failed:   q.failed.Load(),
+		Buffered: buffered,
+	}
+}
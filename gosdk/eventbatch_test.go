@@ -0,0 +1,59 @@
+package sdkv2_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestAsyncEventLogger(t *testing.T) {
+	gw, err := gateway.Start(gateway.Options{PluginID: "events-test", EventChannel: "soren.plugin.event.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: gw.URL(), PluginID: "events-test", EventChannel: "soren.plugin.event.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.Close()
+
+	logger := sdkv2.NewAsyncEventLogger(sdk, sdkv2.AsyncOptions{BatchSize: 10, FlushInterval: time.Hour})
+	for i := range 25 {
+		if err := logger.Log("test", models.LogLevelInfo, fmt.Sprint(i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	events := gw.Events()
+	if len(events) != 25 {
+		t.Fatalf("got %d events, want 25", len(events))
+	}
+	for i, event := range events {
+		if event.Message != fmt.Sprint(i) {
+			t.Fatalf("event %d is %q, events out of order", i, event.Message)
+		}
+	}
+	if stats := logger.Stats(); stats.Sent != 25 || stats.Dropped != 0 || stats.Buffered != 0 {
+		t.Errorf("stats: %+v", stats)
+	}
+	if err := logger.Log("test", models.LogLevelInfo, "late", nil); err != sdkv2.ErrEventLoggerClosed {
+		t.Errorf("log after close: %v", err)
+	}
+
+	// every event is either sent or counted as dropped
+	logger = sdkv2.NewAsyncEventLogger(sdk, sdkv2.AsyncOptions{BufferSize: 4, BatchSize: 4, Overflow: sdkv2.OverflowSample, SampleEvery: 2})
+	for i := range 100 {
+		logger.Log("test", models.LogLevelInfo, fmt.Sprint(i), nil)
+	}
+	sdk.Close() // drains the logger
+	if stats := logger.Stats(); stats.Sent+stats.Dropped+stats.Failed != 100 {
+		t.Errorf("stats do not add up: %+v", stats)
+	}
+}
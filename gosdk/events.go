@@ -1,6 +1,7 @@
 package sdkv2
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -12,7 +13,10 @@ import (
 
 // EventLogger handles logging and event emission
 type EventLogger struct {
-	sdk *SorenSDK
+	sdk   *SorenSDK
+	queue *eventQueue     // set for asynchronous loggers
+	scope *eventScope     // set by WithContext
+	ctx   context.Context // set by WithContext, its span is propagated with the events
 }
 
 // NewEventLogger creates a new event logger
@@ -23,121 +27,266 @@ func NewEventLogger(sdk *SorenSDK) *EventLogger {
 // Log sends a log event to the Soren platform
 func (e *EventLogger) Log(source string, level models.LogLevel, message string, details map[string]any) error {
 	event := models.PluginEvent{
-		Event:     models.EventTypeLog,
-		Level:     level,
-		Source:    fmt.Sprintf("%s - %s", e.sdk.pluginID, source),
-		Message:   message,
-		Timestamp: uint64(time.Now().Unix()),
-		Details:   details,
+		Event:   models.EventTypeLog,
+		Level:   level,
+		Source:  fmt.Sprintf("%s - %s", e.sdk.pluginID, source),
+		Message: message,
+		Details: details,
 	}
 
-	return e.sendEvent(event)
+	return e.send(event)
 }
 
 // EmitEvent sends a custom event to the Soren platform
 func (e *EventLogger) EmitEvent(eventType models.EventType, data map[string]any) error {
 	event := models.PluginEvent{
-		Event:     eventType,
-		Level:     models.LogLevelInfo,
-		Source:    e.sdk.pluginID,
-		Message:   fmt.Sprintf("Event: %s", eventType),
-		Timestamp: uint64(time.Now().Unix()),
-		Details:   data,
+		Event:   eventType,
+		Level:   models.LogLevelInfo,
+		Source:  e.sdk.pluginID,
+		Message: fmt.Sprintf("Event: %s", eventType),
+		Details: data,
+	}
+
+	return e.send(event)
+}
+
+// Audit sends an audit event recording who did what
+func (e *EventLogger) Audit(audit models.AuditEvent) error {
+	message := strings.TrimSpace(fmt.Sprintf("%s %s %s", audit.Actor, audit.Action, audit.Target))
+	return e.send(e.event(models.EventTypeAudit, models.LogLevelInfo, message, audit))
+}
+
+// Metric sends a measurement
+func (e *EventLogger) Metric(metric models.MetricEvent) error {
+	message := strings.TrimSpace(fmt.Sprintf("%s=%v %s", metric.Name, metric.Value, metric.Unit))
+	return e.send(e.event(models.EventTypeMetric, models.LogLevelInfo, message, metric))
+}
+
+// Alert sends an alert, its severity sets the event level
+func (e *EventLogger) Alert(alert models.AlertEvent) error {
+	level := models.LogLevelInfo
+	switch alert.Severity {
+	case models.AlertSeverityWarning:
+		level = models.LogLevelWarn
+	case models.AlertSeverityCritical:
+		level = models.LogLevelError
 	}
+	return e.send(e.event(models.EventTypeAlert, level, alert.Title, alert))
+}
 
+// Job sends a job lifecycle event, plugins send them on their own for the jobs they run
+func (e *EventLogger) Job(job models.JobEvent) error {
+	level := models.LogLevelInfo
+	if job.Status == models.JobStatusFailed {
+		level = models.LogLevelError
+	}
+	message := fmt.Sprintf("job %s %s", job.JobID, job.Status)
+	event := e.event(models.EventTypeJob, level, message, job)
+	event.JobID = job.JobID
+	event.EntityID = job.EntityID
+	return e.send(event)
+}
+
+// event makes an event of the given type with payload as its details
+func (e *EventLogger) event(eventType models.EventType, level models.LogLevel, message string, payload any) models.PluginEvent {
+	details := map[string]any{}
+	if body, err := json.Marshal(payload); err == nil {
+		json.Unmarshal(body, &details)
+	}
+	return models.PluginEvent{
+		Event:   eventType,
+		Level:   level,
+		Source:  e.sdk.pluginID,
+		Message: message,
+		Details: details,
+	}
+}
+
+// send buffers the event of an asynchronous logger, or sends it right away
+func (e *EventLogger) send(event models.PluginEvent) error {
+	e.stamp(&event)
+	if e.queue != nil {
+		return e.queue.push(event)
+	}
 	return e.sendEvent(event)
 }
 
 // sendEvent sends an event to the Soren platform
 func (e *EventLogger) sendEvent(event models.PluginEvent) error {
-	if e.sdk.eventChannel == "" {
-		return fmt.Errorf("event channel not configured")
+	if err := e.publish([]models.PluginEvent{event}, false); err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
 	}
+	return nil
+}
 
-	body, err := json.Marshal([]models.PluginEvent{event})
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+// SendMultipleEvents sends multiple events in a single request,
+// or one per subject when the events are routed to different subjects.
+// When only some subjects fail the error is a *PublishError, the events it does not list were sent.
+func (e *EventLogger) SendMultipleEvents(events ...models.PluginEvent) error {
+	if err := e.publish(events, true); err != nil {
+		return fmt.Errorf("failed to send events: %w", err)
 	}
-	subject := fmt.Sprintf("%s.%s.log", e.sdk.eventChannel, e.sdk.pluginID)
-	if len(strings.Split(e.sdk.pluginID, ".")) > 0 {
-		subject = fmt.Sprintf("%s.log", e.sdk.eventChannel)
+	return nil
+}
 
+// FailedBatch is the events of a subject that could not be sent
+type FailedBatch struct {
+	Subject string
+	Events  []models.PluginEvent
+	Err     error
+}
+
+// PublishError lists the subjects whose events could not be sent.
+// The events of the other subjects were delivered and must not be sent again.
+type PublishError struct {
+	Failed []FailedBatch
+}
+
+func (e *PublishError) Error() string {
+	failures := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		failures = append(failures, fmt.Sprintf("%s: %v", f.Subject, f.Err))
 	}
+	return strings.Join(failures, "; ")
+}
 
-	msg := &nats.Msg{
-		Subject: subject,
-		Data:    body,
+func (e *PublishError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		errs = append(errs, f.Err)
 	}
+	return errs
+}
 
-	// Only add Authorization header if authKey is set
-	if e.sdk.authKey != "" {
-		msg.Header = nats.Header{"Authorization": []string{e.sdk.authKey}}
+// events returns the number of events that could not be sent
+func (e *PublishError) events() int {
+	n := 0
+	for _, f := range e.Failed {
+		n += len(f.Events)
 	}
+	return n
+}
 
-	resp, err := e.sdk.conn.RequestMsg(msg, 3*time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to send event: %w", err)
+// publish sends events to their subjects, keeping their order within each subject.
+// A subject that fails does not stop the others, the failed ones are returned as a *PublishError.
+func (e *EventLogger) publish(events []models.PluginEvent, batch bool) error {
+	if e.sdk.eventChannel == "" {
+		return fmt.Errorf("event channel not configured")
 	}
-	
-	// Only print response if it contains an error
-	if len(resp.Data) > 0 {
-		var response map[string]interface{}
-		if err := json.Unmarshal(resp.Data, &response); err == nil {
-			if errMsg, ok := response["error"].(string); ok && errMsg != "" {
-				fmt.Printf("Event logging error: %s\n", errMsg)
-			}
+	subjects := []string{}
+	batches := map[string][]models.PluginEvent{}
+	for _, event := range events {
+		subject := e.sdk.eventSubject(event.Event, batch)
+		if _, ok := batches[subject]; !ok {
+			subjects = append(subjects, subject)
 		}
+		batches[subject] = append(batches[subject], event)
 	}
+	failed := []FailedBatch{}
+	for _, subject := range subjects {
+		if err := e.publishBatch(subject, batches[subject]); err != nil {
+			failed = append(failed, FailedBatch{Subject: subject, Events: batches[subject], Err: err})
+		}
+	}
+	if len(failed) > 0 {
+		return &PublishError{Failed: failed}
+	}
+	return nil
+}
+
+// publishBatch sends the events of a subject
+func (e *EventLogger) publishBatch(subject string, events []models.PluginEvent) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %w", err)
+	}
+	resp, err := e.deliver(subject, body)
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return nil
+	}
+	return eventsResult(resp)
+}
+
+// eventsResult returns the error of the platform response to a batch of events, errEventsRejected wrapped
+func eventsResult(resp *nats.Msg) error {
 	var response map[string]interface{}
 	if err := json.Unmarshal(resp.Data, &response); err != nil {
-		return fmt.Errorf("failed to unmarshal response: %w", err)
+		return fmt.Errorf("%w: invalid response: %v", errEventsRejected, err)
+	}
+	// Only print response if it contains an error
+	if errMsg, ok := response["error"].(string); ok && errMsg != "" {
+		eventsLogger().Errorw("event logging error", "error", errMsg)
 	}
-
 	// Check if the response indicates success
 	if result, ok := response["result"].(string); ok && result != "OK" {
-		return fmt.Errorf("event sending failed: %s", result)
+		return fmt.Errorf("%w: %s", errEventsRejected, result)
 	}
-
 	return nil
 }
 
-// SendMultipleEvents sends multiple events in a single request
-func (e *EventLogger) SendMultipleEvents(events ...models.PluginEvent) error {
-	if e.sdk.eventChannel == "" {
-		return fmt.Errorf("event channel not configured")
+// deliver sends a batch of events to subject. With an event spool, batches that cannot be delivered
+// and batches sent while older ones wait in the spool are spooled and a nil response is returned.
+func (e *EventLogger) deliver(subject string, body []byte) (*nats.Msg, error) {
+	spool := e.sdk.spool
+	if spool != nil && (spool.pending() || !e.sdk.conn.IsConnected()) {
+		return nil, spool.append(subject, body)
 	}
-
-	body, err := json.Marshal(events)
+	ctx := e.ctx
+	if e.queue != nil {
+		// a batch mixes events of several requests
+		ctx = nil
+	}
+	resp, err := e.sdk.sendEvents(ctx, subject, body)
 	if err != nil {
-		return fmt.Errorf("failed to marshal events: %w", err)
+		e.sdk.metrics.eventFailures.add(1)
 	}
+	if err != nil && spool != nil {
+		if serr := spool.append(subject, body); serr == nil {
+			eventsLogger().Warnw("event delivery failed, spooled", "error", err)
+			return nil, nil
+		}
+	}
+	return resp, err
+}
 
-	subject := fmt.Sprintf("%s.%s.log", e.sdk.eventChannel, e.sdk.pluginID)
-
+// sendEvents makes the request of a batch of events, with the trace headers of the span in ctx
+func (s *SorenSDK) sendEvents(ctx context.Context, subject string, body []byte) (*nats.Msg, error) {
 	msg := &nats.Msg{
 		Subject: subject,
 		Data:    body,
 	}
+	s.injectTrace(ctx, msg)
 
 	// Only add Authorization header if authKey is set
-	if e.sdk.authKey != "" {
-		msg.Header = nats.Header{"Authorization": []string{e.sdk.authKey}}
+	if s.authKey != "" {
+		if msg.Header == nil {
+			msg.Header = nats.Header{}
+		}
+		msg.Header.Set("Authorization", s.authKey)
 	}
 
-	resp, err := e.sdk.conn.RequestMsg(msg, 3*time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to send events: %w", err)
-	}
+	return s.requestMsg(msg, 3*time.Second)
+}
 
-	var response map[string]interface{}
-	if err := json.Unmarshal(resp.Data, &response); err != nil {
-		return fmt.Errorf("failed to unmarshal response: %w", err)
+// replaySpool sends the spooled events in order, rejected events are moved to the dead-letter file
+func (s *SorenSDK) replaySpool() {
+	if s.spool == nil || !s.spool.pending() {
+		return
 	}
-
-	// Check if the response indicates success
-	if result, ok := response["result"].(string); ok && result != "OK" {
-		return fmt.Errorf("events sending failed: %s", result)
+	err := s.spool.replay(func(subject string, body []byte) error {
+		resp, err := s.sendEvents(nil, subject, body)
+		if err == nil {
+			err = eventsResult(resp)
+		}
+		if err != nil {
+			s.metrics.eventFailures.add(1)
+		}
+		return err
+	})
+	if err != nil {
+		eventsLogger().Warnw("event spool replay stopped", "error", err)
 	}
-
-	return nil
 }
@@ -0,0 +1,303 @@
+package sdkv2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestJobEvents(t *testing.T) {
+	gw, err := gateway.Start(gateway.Options{PluginID: "bin.*.job-events", EventChannel: "soren.plugin.event.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: gw.URL(), PluginID: "bin.*.job-events", EventChannel: "soren.plugin.event.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := sdkv2.NewPlugin(sdk)
+	plugin.SetIntro(models.PluginIntro{Name: "Job Events", Version: "1.0.0"}, nil)
+	plugin.AddActions([]models.Action{{Method: "run", Title: "Run"}, {Method: "break", Title: "Break"}, {Method: "stop", Title: "Stop"}})
+	plugin.Handle("run", func(req *sdkv2.Request) {
+		req.Accept()
+		req.Job().Progress(models.JobProgress{Progress: 50})
+		req.Job().Done(nil)
+	})
+	plugin.Handle("break", func(req *sdkv2.Request) {
+		req.Accept()
+		req.Job().Fail(sdkv2.NewError(sdkv2.CodeUnavailable, "broken"))
+	})
+	stopped := make(chan string, 1)
+	plugin.Handle("stop", func(req *sdkv2.Request) {
+		jobId := req.Accept()
+		req.Job().Command(models.StopCommand, models.JobProgress{})
+		stopped <- jobId
+	})
+	go plugin.Start()
+	if _, err := gw.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{"run", "break"} {
+		inv, err := gw.Invoke(method, map[string]any{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range inv.Updates {
+		}
+	}
+	inv, err := gw.Invoke("stop", map[string]any{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the stop command ends the updates
+	for range inv.Updates {
+	}
+	// a stopped job is over, it is not kept
+	if jobId := <-stopped; jobId == "" {
+		t.Error("stop job not accepted")
+	} else if _, ok := sdkv2.GetjobsHolder().Get(jobId); ok {
+		t.Errorf("stopped job %s still held", jobId)
+	}
+	sdk.Close() // sends the buffered events
+
+	got := []string{}
+	for _, event := range gw.Events() {
+		if event.Event != models.EventTypeJob {
+			continue
+		}
+		status, _ := event.Details["status"].(string)
+		got = append(got, status)
+		if event.Details["jobId"] == "" || event.Details["entityId"] != "local" {
+			t.Errorf("job event without job or entity: %v", event.Details)
+		}
+		if status == string(models.JobStatusAccepted) && event.Details["method"] == "" {
+			t.Errorf("accepted event without method: %v", event.Details)
+		}
+		if status == string(models.JobStatusFailed) {
+			if event.Level != models.LogLevelError || event.Details["error"] == nil {
+				t.Errorf("failed event: %+v", event)
+			}
+		}
+	}
+	want := "[accepted progress done accepted failed accepted cancelled]"
+	if s := fmt.Sprint(got); s != want {
+		t.Fatalf("job events %s, want %s", s, want)
+	}
+}
+
+func TestEventSubjects(t *testing.T) {
+	const channel = "soren.plugin.event.test"
+	tests := []struct {
+		name     string
+		pluginID string
+		subject  sdkv2.EventSubjectFunc
+		want     []string // subjects of the requests, in order
+		events   string   // messages as received
+	}{
+		// a batch goes to the plugin log subject, a single event to the channel log subject
+		{"default", "scanner", nil, []string{channel + ".scanner.log", channel + ".log"}, "[1 2 3 4]"},
+		{"by type", "scanner", sdkv2.EventSubjectByType, []string{channel + ".log", channel + ".metric", channel + ".log"}, "[1 3 2 4]"},
+		{"by type bin", "bin.*.4f1c", sdkv2.EventSubjectByType, []string{channel + ".log", channel + ".metric", channel + ".log"}, "[1 3 2 4]"},
+		{"by plugin", "scanner", sdkv2.EventSubjectByPlugin, []string{channel + ".scanner.log", channel + ".scanner.metric", channel + ".scanner.log"}, "[1 3 2 4]"},
+		{"by plugin bin", "bin.*.4f1c", sdkv2.EventSubjectByPlugin, []string{channel + ".bin.4f1c.log", channel + ".bin.4f1c.metric", channel + ".bin.4f1c.log"}, "[1 3 2 4]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw, err := gateway.Start(gateway.Options{PluginID: tt.pluginID, EventChannel: channel})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer gw.Close()
+			subjects := make(chan string, 10)
+			if _, err := gw.Conn().Subscribe(channel+".>", func(msg *nats.Msg) { subjects <- msg.Subject }); err != nil {
+				t.Fatal(err)
+			}
+			gw.Conn().Flush()
+			sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: gw.URL(), PluginID: tt.pluginID, EventChannel: channel, EventSubject: tt.subject})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.Close()
+			logger := sdkv2.NewEventLogger(sdk)
+
+			// with routing by type a mixed batch is split by subject, events of a subject stay together
+			err = logger.SendMultipleEvents(
+				models.PluginEvent{Event: models.EventTypeLog, Message: "1"},
+				models.PluginEvent{Event: models.EventTypeMetric, Message: "2"},
+				models.PluginEvent{Event: models.EventTypeLog, Message: "3"},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := logger.Log("test", models.LogLevelInfo, "4", nil); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for range tt.want {
+				select {
+				case subject := <-subjects:
+					got = append(got, subject)
+				case <-time.After(2 * time.Second):
+					t.Fatalf("got subjects %v, want more", got)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("subjects %v, want %v", got, tt.want)
+			}
+			messages := []string{}
+			for _, event := range gw.Events() {
+				messages = append(messages, event.Message)
+			}
+			if fmt.Sprint(messages) != tt.events {
+				t.Errorf("events %v, want %s", messages, tt.events)
+			}
+		})
+	}
+}
+
+func TestPartialPublishFailure(t *testing.T) {
+	const channel = "soren.plugin.event.partial"
+	gw, err := gateway.Start(gateway.Options{PluginID: "partial"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	delivered := make(chan string, 10)
+	gw.Conn().Subscribe(channel+".log", func(msg *nats.Msg) {
+		delivered <- string(msg.Data)
+		msg.Respond([]byte(`{"result":"OK"}`))
+	})
+	gw.Conn().Subscribe(channel+".metric", func(msg *nats.Msg) {
+		msg.Respond([]byte(`{"result":"FULL"}`))
+	})
+	gw.Conn().Flush()
+	sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: gw.URL(), PluginID: "partial", EventChannel: channel, EventSubject: sdkv2.EventSubjectByType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.Close()
+	events := []models.PluginEvent{
+		{Event: models.EventTypeLog, Message: "1"},
+		{Event: models.EventTypeMetric, Message: "2"},
+		{Event: models.EventTypeLog, Message: "3"},
+	}
+
+	err = sdkv2.NewEventLogger(sdk).SendMultipleEvents(events...)
+	var perr *sdkv2.PublishError
+	if !errors.As(err, &perr) {
+		t.Fatalf("error %v, want a PublishError", err)
+	}
+	if len(perr.Failed) != 1 || perr.Failed[0].Subject != channel+".metric" || len(perr.Failed[0].Events) != 1 || perr.Failed[0].Events[0].Message != "2" {
+		t.Errorf("failed batches %+v", perr.Failed)
+	}
+	select {
+	case data := <-delivered:
+		if !strings.Contains(data, `"message":"1"`) || !strings.Contains(data, `"message":"3"`) {
+			t.Errorf("log batch %s", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the log subject got nothing after the metric one failed")
+	}
+
+	// an asynchronous logger counts only the events of the failed subject as failed
+	async := sdkv2.NewAsyncEventLogger(sdk, sdkv2.AsyncOptions{})
+	for _, event := range events {
+		async.Log("test", models.LogLevelInfo, event.Message, nil)
+		async.Metric(models.MetricEvent{Name: "m", Value: 1})
+	}
+	async.Close()
+	if stats := async.Stats(); stats.Sent != 3 || stats.Failed != 3 {
+		t.Errorf("stats %+v, want 3 sent and 3 failed", stats)
+	}
+}
+
+func TestEventScope(t *testing.T) {
+	const channel = "soren.plugin.event.test"
+	gw, err := gateway.Start(gateway.Options{PluginID: "scoped", EventChannel: channel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: gw.URL(), PluginID: "scoped", EventChannel: channel, LogEventLevel: "warn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := sdkv2.NewPlugin(sdk)
+	plugin.SetIntro(models.PluginIntro{Name: "Scoped", Version: "1.0.0"}, nil)
+	plugin.AddActions([]models.Action{{Method: "run", Title: "Run"}})
+	logger := sdkv2.NewEventLogger(sdk)
+	plugin.Handle("run", func(req *sdkv2.Request) {
+		req.Accept()
+		logger.WithContext(req.Context()).Log("run", models.LogLevelInfo, "scoped", nil)
+		// bound without WithContext
+		req.Events().Log("run", models.LogLevelInfo, "request events", nil)
+		req.Job().Events().Log("run", models.LogLevelInfo, "job events", nil)
+		req.Logger().Warn("request log")
+		req.Job().Logger().Warn("job log")
+		req.Job().Done(nil)
+	})
+	go plugin.Start()
+	if _, err := gw.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	const trace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	msg := nats.NewMsg("soren.cpu.scoped.run")
+	msg.Data = []byte(`{"body":{}}`)
+	msg.Header.Set("X-Correlation-Id", "corr-1")
+	msg.Header.Set("traceparent", "00-"+trace+"-00f067aa0ba902b7-01")
+	before := time.Now()
+	if _, err := gw.Conn().RequestMsg(msg, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	sdk.Close()
+
+	var scoped, accepted *models.PluginEvent
+	bound := map[string]models.PluginEvent{}
+	for _, event := range gw.Events() {
+		if event.Message == "scoped" {
+			scoped = &event
+		}
+		switch event.Message {
+		case "request events", "job events", "request log", "job log":
+			bound[event.Message] = event
+		}
+		if event.Event == models.EventTypeJob && event.Details["status"] == string(models.JobStatusAccepted) {
+			accepted = &event
+		}
+	}
+	if scoped == nil || accepted == nil {
+		t.Fatalf("events missing: %+v", gw.Events())
+	}
+	if scoped.JobID == "" || scoped.JobID != accepted.JobID {
+		t.Errorf("scoped event job %q, accepted job %q", scoped.JobID, accepted.JobID)
+	}
+	if scoped.CorrelationID != "corr-1" || scoped.TraceID != trace {
+		t.Errorf("scoped event ids: %+v", scoped)
+	}
+	if accepted.CorrelationID != "corr-1" || accepted.TraceID != trace {
+		t.Errorf("accepted event ids: %+v", accepted)
+	}
+	for _, message := range []string{"request events", "job events", "request log", "job log"} {
+		event, ok := bound[message]
+		if !ok {
+			t.Errorf("no %q event", message)
+			continue
+		}
+		if event.JobID != accepted.JobID || event.CorrelationID != "corr-1" || event.TraceID != trace {
+			t.Errorf("%q event ids: %+v", message, event)
+		}
+	}
+	if scoped.Time.Before(before.Truncate(time.Millisecond)) || scoped.Timestamp != uint64(scoped.Time.Unix()) {
+		t.Errorf("event time %v, timestamp %d", scoped.Time, scoped.Timestamp)
+	}
+}
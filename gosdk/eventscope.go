@@ -0,0 +1,159 @@
+package sdkv2
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// eventScope links events to the job and request they are sent for
+type eventScope struct {
+	JobID         string
+	EntityID      string
+	CorrelationID string
+	TraceID       string
+}
+
+type eventScopeKey struct{}
+
+func withEventScope(ctx context.Context, scope eventScope) context.Context {
+	return context.WithValue(ctx, eventScopeKey{}, scope)
+}
+
+func eventScopeFrom(ctx context.Context) (eventScope, bool) {
+	if ctx == nil {
+		return eventScope{}, false
+	}
+	scope, ok := ctx.Value(eventScopeKey{}).(eventScope)
+	return scope, ok
+}
+
+// WithContext returns a logger whose events carry the job, entity, correlation and trace IDs
+// of the request or job ctx comes from, see Request.Context and Job.Context.
+// The returned logger shares the buffer of an asynchronous logger.
+// Loggers that are not bound this way, e.g. a plain NewEventLogger, send events without the IDs;
+// Request.Events and Job.Events return bound loggers.
+func (e *EventLogger) WithContext(ctx context.Context) *EventLogger {
+	scoped := *e
+	scoped.ctx = ctx
+	if scope, ok := eventScopeFrom(ctx); ok {
+		scoped.scope = &scope
+	}
+	return &scoped
+}
+
+// stamp sets the time of an event and the IDs of the logger scope it does not have yet
+func (e *EventLogger) stamp(event *models.PluginEvent) {
+	now := time.Now()
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+	if event.Timestamp == 0 {
+		event.Timestamp = uint64(event.Time.Unix())
+	}
+	if e.scope == nil {
+		return
+	}
+	if event.JobID == "" {
+		event.JobID = e.scope.JobID
+	}
+	if event.EntityID == "" {
+		event.EntityID = e.scope.EntityID
+	}
+	if event.CorrelationID == "" {
+		event.CorrelationID = e.scope.CorrelationID
+	}
+	if event.TraceID == "" {
+		event.TraceID = e.scope.TraceID
+	}
+}
+
+// Context returns a context carrying the span of the request and the IDs events logged for it
+// are linked with, pass it to EventLogger.WithContext. It is done when the SDK closes.
+func (r *Request) Context() context.Context {
+	ctx := r.plugin.sdk.ctx
+	if r.span != nil {
+		ctx = trace.ContextWithSpan(ctx, r.span)
+	}
+	return withLogger(withEventScope(ctx, r.scope()), r.Logger())
+}
+
+// scope returns the IDs events logged for the request are linked with
+func (r *Request) scope() eventScope {
+	scope := eventScope{
+		EntityID:      r.EntityID,
+		CorrelationID: correlationID(r.Msg.Header),
+		TraceID:       traceID(r.Msg.Header),
+	}
+	if r.span != nil {
+		if sc := r.span.SpanContext(); sc.IsValid() {
+			scope.TraceID = sc.TraceID().String()
+		}
+	}
+	if r.job != nil {
+		scope.JobID = r.job.ID
+		if scope.CorrelationID == "" {
+			scope.CorrelationID = r.job.ID
+		}
+	}
+	return scope
+}
+
+// Events returns the plugin asynchronous event logger bound to the request,
+// its events carry the request IDs without calling WithContext
+func (r *Request) Events() *EventLogger {
+	return r.plugin.eventLogger().WithContext(r.Context())
+}
+
+// Context returns a context carrying the span of the request that started the job and the IDs
+// events logged for it are linked with, pass it to EventLogger.WithContext. It is done when the SDK closes.
+func (j *Job) Context() context.Context {
+	ctx := j.plugin.sdk.ctx
+	if j.spanContext.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, j.spanContext)
+	}
+	return withLogger(withEventScope(ctx, j.scope()), j.Logger())
+}
+
+// scope returns the IDs events logged for the job are linked with
+func (j *Job) scope() eventScope {
+	correlation := j.correlationID
+	if correlation == "" {
+		correlation = j.ID
+	}
+	return eventScope{
+		JobID:         j.ID,
+		EntityID:      j.EntityID,
+		CorrelationID: correlation,
+		TraceID:       j.traceID,
+	}
+}
+
+// Events returns the plugin asynchronous event logger bound to the job,
+// its events carry the job IDs without calling WithContext
+func (j *Job) Events() *EventLogger {
+	return j.plugin.eventLogger().WithContext(j.Context())
+}
+
+// correlationID returns the correlation ID a request was sent with
+func correlationID(header nats.Header) string {
+	for _, key := range []string{"X-Correlation-Id", "Correlation-Id", "X-Request-Id"} {
+		if v := header.Get(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// traceID returns the trace ID of a W3C traceparent header, version-traceid-parentid-flags
+func traceID(header nats.Header) string {
+	parts := strings.Split(header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	return parts[1]
+}
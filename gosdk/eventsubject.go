@@ -0,0 +1,44 @@
+package sdkv2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// EventSubjectFunc returns the subject events of eventType are sent to
+type EventSubjectFunc func(channel, pluginID string, eventType models.EventType) string
+
+// EventSubjectByType sends events to <channel>.<event type>, e.g. soren.plugin.event.log.
+// The plugin is told by the event source.
+func EventSubjectByType(channel, pluginID string, eventType models.EventType) string {
+	return fmt.Sprintf("%s.%s", channel, eventType)
+}
+
+// EventSubjectByPlugin sends events to <channel>.<plugin ID>.<event type>. The wildcard of bin.*
+// plugins is left out, as subjects cannot contain one: soren.plugin.event.bin.<uuid>.log
+func EventSubjectByPlugin(channel, pluginID string, eventType models.EventType) string {
+	tokens := []string{}
+	for _, token := range strings.Split(pluginID, ".") {
+		if token != "*" && token != ">" && token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return fmt.Sprintf("%s.%s.%s", channel, strings.Join(tokens, "."), eventType)
+}
+
+// eventSubject returns the subject events of eventType are sent to. Without an EventSubjectFunc
+// every event goes to the log subject: <channel>.<plugin ID>.log for a batch, <channel>.log otherwise.
+func (s *SorenSDK) eventSubject(eventType models.EventType, batch bool) string {
+	if s.eventSubjectFunc == nil {
+		if batch {
+			return fmt.Sprintf("%s.%s.log", s.eventChannel, s.pluginID)
+		}
+		return fmt.Sprintf("%s.log", s.eventChannel)
+	}
+	if eventType == "" {
+		eventType = models.EventTypeLog
+	}
+	return s.eventSubjectFunc(s.eventChannel, s.pluginID, eventType)
+}
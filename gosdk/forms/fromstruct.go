@@ -0,0 +1,298 @@
+package forms
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/jsonui"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// FromStruct derives the JSON schema of a form from the fields of struct T and a vertical layout
+// of its controls, nested structs becoming groups. Fields are named after their json tag and are
+// required unless tagged omitempty or a pointer. Other tags:
+//
+//	title:"Project"            property title, also the label of the control
+//	description:"..."          property description
+//	enum:"low,medium,high"     allowed values, converted to the field type
+//	format:"email"             string format
+//	secret:"true"              password input
+//	multi:"true"               text area input
+//	minLength, maxLength, minimum, maximum, pattern and default
+//
+// []byte and [N]byte fields are base64 strings. Types that refer to themselves are an error.
+// The result plugs into an action, Jsonui and Jsonschema can be copied into Settings and Requirements.
+func FromStruct[T any]() (models.ActionFormBuilder, error) {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return models.ActionFormBuilder{}, fmt.Errorf("forms: %s is not a struct", t)
+	}
+	schema, elements, err := structSchema(t, "", map[reflect.Type]bool{})
+	if err != nil {
+		return models.ActionFormBuilder{}, err
+	}
+	return models.ActionFormBuilder{
+		Jsonui:     jsonui.VerticalLayout(elements...).Map(),
+		Jsonschema: schema,
+	}, nil
+}
+
+// structSchema returns the object schema of t and the controls of its fields, scoped under path.
+// seen holds the struct types being expanded, a type found again refers to itself.
+func structSchema(t reflect.Type, path string, seen map[reflect.Type]bool) (map[string]any, []*jsonui.Element, error) {
+	if seen[t] {
+		return nil, nil, fmt.Errorf("forms: %s refers to itself", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	properties := map[string]any{}
+	required := []any{}
+	elements := []*jsonui.Element{}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || len(field.Index) > 1 {
+			continue
+		}
+		name, omitempty := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		// embedded structs without a json name are flattened like encoding/json does
+		if field.Anonymous && field.Tag.Get("json") == "" && derefType(fieldType).Kind() == reflect.Struct {
+			schema, children, err := structSchema(derefType(fieldType), path, seen)
+			if err != nil {
+				return nil, nil, err
+			}
+			for k, v := range schema["properties"].(map[string]any) {
+				properties[k] = v
+			}
+			required = append(required, schema["required"].([]any)...)
+			elements = append(elements, children...)
+			continue
+		}
+		scope := name
+		if path != "" {
+			scope = path + "." + name
+		}
+		property, children, err := fieldSchema(field, fieldType, scope, seen)
+		if err != nil {
+			return nil, nil, fmt.Errorf("forms: field %s: %w", field.Name, err)
+		}
+		properties[name] = property
+		if !omitempty && fieldType.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+		elements = append(elements, fieldElement(field, scope, property, children))
+	}
+	schema := map[string]any{"type": "object", "properties": properties, "required": required}
+	return schema, elements, nil
+}
+
+// fieldSchema returns the property schema of a field, with the controls of its fields for structs
+func fieldSchema(field reflect.StructField, t reflect.Type, scope string, seen map[reflect.Type]bool) (map[string]any, []*jsonui.Element, error) {
+	t = derefType(t)
+	var schema map[string]any
+	var children []*jsonui.Element
+	var err error
+	if t.Kind() == reflect.Struct && t != reflect.TypeFor[time.Time]() {
+		schema, children, err = structSchema(t, scope, seen)
+	} else {
+		schema, err = typeSchema(t, seen)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	tags := field.Tag
+	if v, ok := tags.Lookup("title"); ok {
+		schema["title"] = v
+	}
+	if v, ok := tags.Lookup("description"); ok {
+		schema["description"] = v
+	}
+	if v, ok := tags.Lookup("format"); ok {
+		schema["format"] = v
+	}
+	if v, ok := tags.Lookup("pattern"); ok {
+		schema["pattern"] = v
+	}
+	for _, key := range []string{"minLength", "maxLength", "minimum", "maximum"} {
+		v, ok := tags.Lookup(key)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s %q", key, v)
+		}
+		schema[key] = n
+	}
+	// enums of slices apply to their items, []byte is a string without items
+	valueType := t
+	target := schema
+	if items, ok := schema["items"].(map[string]any); ok {
+		valueType = derefType(t.Elem())
+		target = items
+	}
+	if v, ok := tags.Lookup("enum"); ok {
+		values := []any{}
+		for _, item := range strings.Split(v, ",") {
+			value, err := parseValue(strings.TrimSpace(item), valueType)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid enum: %w", err)
+			}
+			values = append(values, value)
+		}
+		target["enum"] = values
+	}
+	if v, ok := tags.Lookup("default"); ok {
+		value, err := parseValue(v, t)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid default: %w", err)
+		}
+		schema["default"] = value
+	}
+	return schema, children, nil
+}
+
+// fieldElement returns the control of a field, a group of controls for structs
+func fieldElement(field reflect.StructField, scope string, schema map[string]any, children []*jsonui.Element) *jsonui.Element {
+	title, _ := schema["title"].(string)
+	if children != nil {
+		if title == "" {
+			title = field.Name
+		}
+		return jsonui.Group(title, children...)
+	}
+	control := jsonui.Control(scope)
+	if title != "" {
+		control.WithLabel(title)
+	}
+	if b, _ := strconv.ParseBool(field.Tag.Get("secret")); b {
+		control.Format("password")
+	}
+	if b, _ := strconv.ParseBool(field.Tag.Get("multi")); b {
+		control.Multi()
+	}
+	return control
+}
+
+// typeSchema returns the schema of a non struct type
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) (map[string]any, error) {
+	switch jsonType(t) {
+	case "string":
+		if t == reflect.TypeFor[time.Time]() {
+			return map[string]any{"type": "string", "format": "date-time"}, nil
+		}
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		return map[string]any{"type": "string"}, nil
+	case "array":
+		elem := derefType(t.Elem())
+		var items map[string]any
+		var err error
+		if elem.Kind() == reflect.Struct && elem != reflect.TypeFor[time.Time]() {
+			items, _, err = structSchema(elem, "", seen)
+		} else {
+			items, err = typeSchema(elem, seen)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case "object":
+		values, err := typeSchema(derefType(t.Elem()), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case "":
+		// interfaces accept any value
+		return map[string]any{}, nil
+	}
+	return map[string]any{"type": jsonType(t)}, nil
+}
+
+// jsonType returns the JSON schema type of a non struct type, empty for any value
+func jsonType(t reflect.Type) string {
+	switch {
+	case t == reflect.TypeFor[time.Time]():
+		return "string"
+	case t == reflect.TypeFor[time.Duration]():
+		return "integer"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return ""
+}
+
+// parseValue converts a tag value to the JSON value of type t
+func parseValue(v string, t reflect.Type) (any, error) {
+	switch jsonType(t) {
+	case "boolean":
+		return strconv.ParseBool(v)
+	case "integer":
+		return strconv.ParseInt(v, 10, 64)
+	case "number":
+		return strconv.ParseFloat(v, 64)
+	case "array":
+		values := []any{}
+		if v == "" {
+			return values, nil
+		}
+		for _, item := range strings.Split(v, ",") {
+			value, err := parseValue(strings.TrimSpace(item), derefType(t.Elem()))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return v, nil
+}
+
+// jsonName returns the JSON property name of a field and whether it is omitempty
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	omitempty := false
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitempty = true
+		}
+	}
+	return name, omitempty
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
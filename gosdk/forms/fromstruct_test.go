@@ -0,0 +1,113 @@
+package forms
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+type scanInput struct {
+	Project  string   `json:"project" title:"Project" minLength:"3"`
+	Severity string   `json:"severity,omitempty" enum:"low,medium,high" default:"medium"`
+	Token    string   `json:"token" secret:"true"`
+	Retries  int      `json:"retries,omitempty" minimum:"0" maximum:"5"`
+	Tags     []string `json:"tags,omitempty"`
+	Repo     struct {
+		URL    string `json:"url" format:"uri"`
+		Branch string `json:"branch,omitempty"`
+	} `json:"repo" title:"Repository"`
+	Internal string `json:"-"`
+}
+
+func TestFromStruct(t *testing.T) {
+	form, err := FromStruct[scanInput]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := form.Jsonschema["properties"].(map[string]any)
+	if !reflect.DeepEqual(form.Jsonschema["required"], []any{"project", "token", "repo"}) {
+		t.Errorf("required: %v", form.Jsonschema["required"])
+	}
+	if _, ok := props["Internal"]; ok {
+		t.Error("json:\"-\" field in schema")
+	}
+	severity := props["severity"].(map[string]any)
+	if !reflect.DeepEqual(severity["enum"], []any{"low", "medium", "high"}) || severity["default"] != "medium" {
+		t.Errorf("severity: %v", severity)
+	}
+	repo := props["repo"].(map[string]any)
+	if repo["title"] != "Repository" || repo["properties"].(map[string]any)["url"].(map[string]any)["format"] != "uri" {
+		t.Errorf("repo: %v", repo)
+	}
+
+	elements := form.Jsonui["elements"].([]any)
+	token := elements[2].(map[string]any)
+	if token["scope"] != "#/properties/token" || token["options"].(map[string]any)["format"] != "password" {
+		t.Errorf("token control: %v", token)
+	}
+	group := elements[5].(map[string]any)
+	url := group["elements"].([]any)[0].(map[string]any)
+	if group["type"] != "Group" || url["scope"] != "#/properties/repo/properties/url" {
+		t.Errorf("repo group: %v", group)
+	}
+
+	if problems := Lint(models.PluginIntro{Name: "test"}, nil, []models.Action{{Method: "scan", Title: "Scan", Form: form}}); len(problems) > 0 {
+		t.Errorf("lint: %v", problems)
+	}
+	// a sample of the schema decodes into the struct
+	data, _ := json.Marshal(SampleObject(form.Jsonschema))
+	if err := json.Unmarshal(data, &scanInput{}); err != nil {
+		t.Errorf("sample %s: %v", data, err)
+	}
+}
+
+type uploadInput struct {
+	Name     string   `json:"name"`
+	Content  []byte   `json:"content" title:"Content"`
+	Checksum [32]byte `json:"checksum,omitempty" enum:"a,b"`
+}
+
+func TestFromStructBytes(t *testing.T) {
+	form, err := FromStruct[uploadInput]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := form.Jsonschema["properties"].(map[string]any)
+	for _, name := range []string{"content", "checksum"} {
+		if props[name].(map[string]any)["type"] != "string" {
+			t.Errorf("%s: %v, want a base64 string", name, props[name])
+		}
+	}
+	if !reflect.DeepEqual(props["checksum"].(map[string]any)["enum"], []any{"a", "b"}) {
+		t.Errorf("checksum enum: %v", props["checksum"])
+	}
+}
+
+type node struct {
+	Name string `json:"name"`
+	Next *node  `json:"next,omitempty"`
+}
+
+type tree struct {
+	Name     string `json:"name"`
+	Children []tree `json:"children,omitempty"`
+}
+
+type pair struct {
+	Left  struct{ Name string } `json:"left"`
+	Right struct{ Name string } `json:"right"` // the same type twice is not a cycle
+}
+
+func TestFromStructCycles(t *testing.T) {
+	if _, err := FromStruct[node](); err == nil {
+		t.Error("self referencing pointer field accepted")
+	}
+	if _, err := FromStruct[tree](); err == nil {
+		t.Error("self referencing slice field accepted")
+	}
+	if _, err := FromStruct[pair](); err != nil {
+		t.Errorf("repeated type: %v", err)
+	}
+}
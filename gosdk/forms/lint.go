@@ -0,0 +1,94 @@
+package forms
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// Problem is an issue found in a plugin form
+type Problem struct {
+	Form    string // "requirements", "settings" or "action <method>"
+	Path    string // JSON pointer like location inside the form
+	Message string
+}
+
+func (p Problem) Error() string {
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s", p.Form, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Form, p.Path, p.Message)
+}
+
+// Lint checks the forms of a plugin and its action declarations
+func Lint(intro models.PluginIntro, settings *models.Settings, actions []models.Action) []Problem {
+	problems := []Problem{}
+	if strings.TrimSpace(intro.Name) == "" {
+		problems = append(problems, Problem{Form: "intro", Path: "name", Message: "is required"})
+	}
+	if intro.Requirements != nil {
+		problems = append(problems, LintForm("requirements", intro.Requirements.Jsonui, intro.Requirements.Jsonschema)...)
+	}
+	if settings != nil {
+		problems = append(problems, LintForm("settings", settings.Jsonui, settings.Jsonschema)...)
+	}
+	seen := map[string]bool{}
+	for i, action := range actions {
+		form := fmt.Sprintf("action %s", action.Method)
+		if strings.TrimSpace(action.Method) == "" {
+			form = fmt.Sprintf("action #%d", i)
+			problems = append(problems, Problem{Form: form, Path: "method", Message: "is required"})
+		} else if seen[action.Method] {
+			problems = append(problems, Problem{Form: form, Path: "method", Message: "is declared twice"})
+		}
+		seen[action.Method] = true
+		if strings.TrimSpace(action.Title) == "" {
+			problems = append(problems, Problem{Form: form, Path: "title", Message: "is required"})
+		}
+		problems = append(problems, LintForm(form, action.Form.Jsonui, action.Form.Jsonschema)...)
+	}
+	return problems
+}
+
+// LintForm checks a single form made of a JSON Forms UI schema and a JSON schema:
+// the schema must be a valid draft object schema and every UI scope must resolve in it
+func LintForm(form string, jsonui, jsonschema map[string]any) []Problem {
+	problems := []Problem{}
+	if jsonschema == nil {
+		problems = append(problems, Problem{Form: form, Path: "jsonschema", Message: "is missing"})
+	} else {
+		if t, _ := jsonschema["type"].(string); t != "object" {
+			problems = append(problems, Problem{Form: form, Path: "jsonschema/type", Message: `must be "object"`})
+		}
+		problems = append(problems, lintSchema(form, "jsonschema", jsonschema)...)
+	}
+	if jsonui == nil {
+		problems = append(problems, Problem{Form: form, Path: "jsonui", Message: "is missing"})
+	} else if t, _ := jsonui["type"].(string); t == "" {
+		problems = append(problems, Problem{Form: form, Path: "jsonui/type", Message: "is required"})
+	} else {
+		problems = append(problems, lintUI(form, "jsonui", jsonui, jsonschema)...)
+	}
+	return problems
+}
+
+// Problems is every problem found in a plugin, as one error
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, 0, len(p)+1)
+	lines = append(lines, fmt.Sprintf("%d form problem(s):", len(p)))
+	for _, problem := range p {
+		lines = append(lines, "  "+problem.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Err returns p as an error, nil when there are no problems
+func (p Problems) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return p
+}
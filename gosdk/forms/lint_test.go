@@ -0,0 +1,59 @@
+package forms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLintForm(t *testing.T) {
+	jsonui := map[string]any{
+		"type": "VerticalLayout",
+		"elements": []map[string]any{
+			{"type": "Control", "scope": "#/properties/project"},
+			{"type": "Control", "scope": "#/properties/missing"},
+			{"type": "Control", "scope": "#/properties/branch", "rule": map[string]any{
+				"effect":    "SHOW",
+				"condition": map[string]any{"scope": "#/properties/mode", "schema": map[string]any{"const": "x"}},
+			}},
+		},
+	}
+	jsonschema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"project": map[string]any{"enum": []string{}},
+			"branch":  map[string]any{"type": "text", "minLength": -1},
+		},
+		"required": []string{"project", "owner"},
+	}
+	got := map[string]bool{}
+	for _, problem := range LintForm("action scan", jsonui, jsonschema) {
+		got[problem.Error()] = true
+	}
+	want := []string{
+		"action scan: jsonschema/properties/project: has no type",
+		"action scan: jsonschema/properties/project/enum: is empty",
+		`action scan: jsonschema/properties/branch/type: unknown type "text"`,
+		"action scan: jsonschema/properties/branch/minLength: must be a non negative integer",
+		"action scan: jsonschema/required: owner is not a property",
+		"action scan: jsonui/elements/1/scope: #/properties/missing does not resolve in the schema",
+		"action scan: jsonui/elements/2/rule/condition/scope: #/properties/mode does not resolve in the schema",
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("missing problem %q", w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d problems, want %d: %v", len(got), len(want), got)
+	}
+
+	err := Problems(LintForm("action scan", jsonui, jsonschema)).Err()
+	var problems Problems
+	if !errors.As(err, &problems) || !strings.HasPrefix(err.Error(), "7 form problem(s):") {
+		t.Errorf("aggregated error: %v", err)
+	}
+	if Problems(nil).Err() != nil {
+		t.Error("no problems must be a nil error")
+	}
+}
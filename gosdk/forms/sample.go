@@ -0,0 +1,127 @@
+package forms
+
+import "sort"
+
+// Sample generates a value that satisfies a JSON schema, using defaults, enums,
+// formats and bounds where the schema declares them
+func Sample(schema map[string]any) any {
+	if def, ok := schema["default"]; ok {
+		return def
+	}
+	if c, ok := schema["const"]; ok {
+		return c
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if enum, ok := schema["enum"].([]string); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	switch schemaType(schema) {
+	case "object":
+		return SampleObject(schema)
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		count := 1
+		if n, ok := number(schema["minItems"]); ok && int(n) > count {
+			count = int(n)
+		}
+		values := make([]any, 0, count)
+		for range count {
+			values = append(values, Sample(items))
+		}
+		return values
+	case "integer":
+		if n, ok := number(schema["minimum"]); ok {
+			return int64(n)
+		}
+		return int64(1)
+	case "number":
+		if n, ok := number(schema["minimum"]); ok {
+			return n
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	default:
+		return sampleString(schema)
+	}
+}
+
+// SampleObject generates an object with every property of an object schema
+func SampleObject(schema map[string]any) map[string]any {
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make(map[string]any, len(names))
+	for _, name := range names {
+		property, _ := properties[name].(map[string]any)
+		values[name] = Sample(property)
+	}
+	return values
+}
+
+func sampleString(schema map[string]any) string {
+	value := "sample"
+	switch schema["format"] {
+	case "email":
+		value = "sample@example.com"
+	case "uri", "url":
+		value = "https://example.com"
+	case "date":
+		value = "2024-01-01"
+	case "date-time":
+		value = "2024-01-01T00:00:00Z"
+	case "uuid":
+		value = "00000000-0000-4000-8000-000000000000"
+	}
+	if n, ok := number(schema["minLength"]); ok {
+		for len(value) < int(n) {
+			value += "x"
+		}
+	}
+	if n, ok := number(schema["maxLength"]); ok && len(value) > int(n) {
+		value = value[:int(n)]
+	}
+	return value
+}
+
+// schemaType returns the type of a schema, the first one when it lists several
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// number reads a JSON number whichever Go type the decoder produced
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,243 @@
+package forms
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// schemaTypes are the types of the JSON schema draft
+var schemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"object": true, "array": true, "null": true,
+}
+
+// lintSchema checks that schema is a valid draft schema, path is its location in the form
+func lintSchema(form, path string, schema map[string]any) []Problem {
+	problems := []Problem{}
+	problem := func(at, message string) {
+		problems = append(problems, Problem{Form: form, Path: path + at, Message: message})
+	}
+	if draft, ok := schema["$schema"]; ok {
+		if uri, _ := draft.(string); !strings.Contains(uri, "json-schema.org") {
+			problem("/$schema", "is not a JSON schema draft")
+		}
+	}
+	switch t := schema["type"].(type) {
+	case nil:
+	case string:
+		if !schemaTypes[t] {
+			problem("/type", fmt.Sprintf("unknown type %q", t))
+		}
+	case []any:
+		for _, item := range t {
+			if name, _ := item.(string); !schemaTypes[name] {
+				problem("/type", fmt.Sprintf("unknown type %v", item))
+			}
+		}
+	default:
+		problem("/type", "must be a string or an array of strings")
+	}
+	if enum, ok := schema["enum"]; ok {
+		if values, ok := toList(enum); !ok {
+			problem("/enum", "must be an array")
+		} else if len(values) == 0 {
+			problem("/enum", "is empty")
+		}
+	}
+	for _, key := range []string{"minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties"} {
+		if v, ok := schema[key]; ok {
+			if n, isNumber := number(v); !isNumber || n < 0 || n != float64(int64(n)) {
+				problem("/"+key, "must be a non negative integer")
+			}
+		}
+	}
+	for _, key := range []string{"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf"} {
+		if v, ok := schema[key]; ok {
+			if _, isNumber := number(v); !isNumber {
+				problem("/"+key, "must be a number")
+			}
+		}
+	}
+	if v, ok := schema["pattern"]; ok {
+		if pattern, isString := v.(string); !isString {
+			problem("/pattern", "must be a string")
+		} else if _, err := regexp.Compile(pattern); err != nil {
+			problem("/pattern", "is not a valid regular expression")
+		}
+	}
+	properties := map[string]any{}
+	if v, ok := schema["properties"]; ok {
+		if props, isMap := v.(map[string]any); isMap {
+			properties = props
+		} else {
+			problem("/properties", "must be an object")
+		}
+	}
+	for name, v := range properties {
+		property, ok := v.(map[string]any)
+		if !ok {
+			problem("/properties/"+name, "must be an object")
+			continue
+		}
+		if !typed(property) {
+			problem("/properties/"+name, "has no type")
+		}
+		problems = append(problems, lintSchema(form, path+"/properties/"+name, property)...)
+	}
+	if v, ok := schema["required"]; ok {
+		if required, isList := toList(v); !isList {
+			problem("/required", "must be an array")
+		} else {
+			for _, item := range required {
+				if name, _ := item.(string); properties[name] == nil {
+					problem("/required", fmt.Sprintf("%v is not a property", item))
+				}
+			}
+		}
+	}
+	if v, ok := schema["items"]; ok {
+		if items, isMap := v.(map[string]any); isMap {
+			problems = append(problems, lintSchema(form, path+"/items", items)...)
+		} else if _, isBool := v.(bool); !isBool {
+			problem("/items", "must be an object")
+		}
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		v, ok := schema[key]
+		if !ok {
+			continue
+		}
+		list, isList := toList(v)
+		if !isList || len(list) == 0 {
+			problem("/"+key, "must be a non empty array")
+			continue
+		}
+		for i, item := range list {
+			if sub, isMap := item.(map[string]any); isMap {
+				problems = append(problems, lintSchema(form, fmt.Sprintf("%s/%s/%d", path, key, i), sub)...)
+			}
+		}
+	}
+	return problems
+}
+
+// typed reports whether a property schema says what values it takes
+func typed(schema map[string]any) bool {
+	for _, key := range []string{"type", "$ref", "const", "allOf", "anyOf", "oneOf"} {
+		if _, ok := schema[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lintUI checks the elements of a UI schema and that their scopes resolve in schema
+func lintUI(form, path string, element map[string]any, schema map[string]any) []Problem {
+	problems := []Problem{}
+	t, _ := element["type"].(string)
+	if t == "" {
+		return append(problems, Problem{Form: form, Path: path + "/type", Message: "is required"})
+	}
+	if scope, ok := element["scope"]; ok || t == "Control" {
+		s, _ := scope.(string)
+		if s == "" {
+			problems = append(problems, Problem{Form: form, Path: path + "/scope", Message: "is required"})
+		} else if schema != nil && resolveScope(schema, s) == nil {
+			problems = append(problems, Problem{Form: form, Path: path + "/scope", Message: fmt.Sprintf("%s does not resolve in the schema", s)})
+		}
+	}
+	if rule, ok := element["rule"].(map[string]any); ok {
+		condition, _ := rule["condition"].(map[string]any)
+		problems = append(problems, lintCondition(form, path+"/rule/condition", condition, schema)...)
+	}
+	if v, ok := element["elements"]; ok {
+		elements, isList := toMaps(v)
+		if !isList {
+			return append(problems, Problem{Form: form, Path: path + "/elements", Message: "must be an array of elements"})
+		}
+		for i, child := range elements {
+			problems = append(problems, lintUI(form, fmt.Sprintf("%s/elements/%d", path, i), child, schema)...)
+		}
+	}
+	return problems
+}
+
+func lintCondition(form, path string, condition map[string]any, schema map[string]any) []Problem {
+	if condition == nil {
+		return []Problem{{Form: form, Path: path, Message: "is missing"}}
+	}
+	if conditions, ok := toMaps(condition["conditions"]); ok {
+		problems := []Problem{}
+		for i, child := range conditions {
+			problems = append(problems, lintCondition(form, fmt.Sprintf("%s/conditions/%d", path, i), child, schema)...)
+		}
+		return problems
+	}
+	scope, _ := condition["scope"].(string)
+	if scope != "" && schema != nil && resolveScope(schema, scope) == nil {
+		return []Problem{{Form: form, Path: path + "/scope", Message: fmt.Sprintf("%s does not resolve in the schema", scope)}}
+	}
+	return nil
+}
+
+// resolveScope returns the sub schema a JSON pointer scope like #/properties/name points to, nil when it does not resolve
+func resolveScope(schema map[string]any, scope string) map[string]any {
+	pointer, ok := strings.CutPrefix(scope, "#")
+	if !ok {
+		return nil
+	}
+	current := schema
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		next, _ := current[token].(map[string]any)
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// toList accepts the []any of decoded JSON as well as typed slices of Go literals
+func toList(v any) ([]any, bool) {
+	switch list := v.(type) {
+	case []any:
+		return list, true
+	case []string:
+		items := make([]any, len(list))
+		for i, item := range list {
+			items[i] = item
+		}
+		return items, true
+	case []int:
+		items := make([]any, len(list))
+		for i, item := range list {
+			items[i] = item
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// toMaps accepts the []any of decoded JSON as well as []map[string]any literals
+func toMaps(v any) ([]map[string]any, bool) {
+	switch list := v.(type) {
+	case []map[string]any:
+		return list, true
+	case []any:
+		maps := make([]map[string]any, 0, len(list))
+		for _, item := range list {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			maps = append(maps, m)
+		}
+		return maps, true
+	}
+	return nil, false
+}
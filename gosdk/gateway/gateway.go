@@ -0,0 +1,410 @@
+// Package gateway simulates the Soren gateway on a local embedded NATS server,
+// to invoke plugin actions and watch their progress without the platform.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// pendingTTL is how long updates of a job nobody tracks are kept
+const pendingTTL = time.Minute
+
+// Options configures a Gateway
+type Options struct {
+	Host         string // 127.0.0.1 by default
+	Port         int    // a random free port when zero
+	PluginID     string // plugin ID as configured in the plugin, bin.*.<uuid> for gateway plugins
+	EntityID     string // entity sending the requests, replaces the bin.* wildcard, "local" by default
+	EventChannel string // subject prefix the plugin sends its events to, captured when set
+	JetStream    bool   // enables JetStream, e.g. for NewKVRateLimitStore
+	Timeout      time.Duration
+	OnUpdate     func(Update)             // called for every job command received
+	OnEvent      func(models.PluginEvent) // called for every event received
+}
+
+// Update is a job command the plugin sent, e.g. a progress report
+type Update struct {
+	JobID    string
+	Command  string
+	Progress models.JobProgress
+	Received time.Time
+	// Dropped counts the updates of the job lost before this one because the reader fell behind
+	Dropped int
+}
+
+// Done reports whether the update ends the job: it is done, failed or stopped
+func (u Update) Done() bool {
+	if u.Command == string(models.StopCommand) {
+		return true
+	}
+	return u.Command == string(models.ProgressCommand) && u.Progress.Progress >= 100
+}
+
+// Invocation is an action request sent to the plugin
+type Invocation struct {
+	Reply models.JobBodyContent
+	// Updates receives the job commands of an accepted request and is closed when the job is done,
+	// failed or stopped. Updates a slow reader misses are counted in Update.Dropped, the final one
+	// is always delivered.
+	// It is nil when the request was rejected.
+	Updates <-chan Update
+}
+
+// Gateway is a local stand-in for the Soren gateway
+type Gateway struct {
+	opts     Options
+	server   *server.Server
+	conn     *nats.Conn
+	storeDir string
+
+	mutex   sync.Mutex
+	jobs    map[string]*trackedJob
+	pending map[string][]Update
+	events  []models.PluginEvent
+}
+
+// Start runs an embedded NATS server and connects the gateway to it
+func Start(opts Options) (*Gateway, error) {
+	if opts.PluginID == "" {
+		return nil, fmt.Errorf("plugin ID is required")
+	}
+	if opts.Host == "" {
+		opts.Host = "127.0.0.1"
+	}
+	if opts.Port == 0 {
+		opts.Port = server.RANDOM_PORT
+	}
+	if opts.EntityID == "" {
+		opts.EntityID = "local"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	g := &Gateway{
+		opts:    opts,
+		jobs:    make(map[string]*trackedJob),
+		pending: make(map[string][]Update),
+	}
+	serverOpts := &server.Options{
+		Host:   opts.Host,
+		Port:   opts.Port,
+		NoLog:  true,
+		NoSigs: true,
+	}
+	if opts.JetStream {
+		dir, err := os.MkdirTemp("", "soren-gateway-")
+		if err != nil {
+			return nil, err
+		}
+		g.storeDir = dir
+		serverOpts.JetStream = true
+		serverOpts.StoreDir = dir
+	}
+	ns, err := server.NewServer(serverOpts)
+	if err != nil {
+		g.cleanup()
+		return nil, fmt.Errorf("failed to create NATS server: %w", err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(opts.Timeout) {
+		ns.Shutdown()
+		g.cleanup()
+		return nil, fmt.Errorf("NATS server did not start")
+	}
+	g.server = ns
+	// no echo, the gateway must not receive the action requests it publishes itself
+	g.conn, err = nats.Connect(ns.ClientURL(), nats.NoEcho())
+	if err != nil {
+		g.Close()
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	if _, err := g.conn.Subscribe(fmt.Sprintf("soren.cpu.%s.>", g.subjectID()), g.handleJobCommand); err != nil {
+		g.Close()
+		return nil, err
+	}
+	if opts.EventChannel != "" {
+		if _, err := g.conn.Subscribe(opts.EventChannel+".>", g.handleEvents); err != nil {
+			g.Close()
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// URL is the URL plugins connect to, their AGENT_URI
+func (g *Gateway) URL() string {
+	return g.server.ClientURL()
+}
+
+// Conn returns the gateway connection
+func (g *Gateway) Conn() *nats.Conn {
+	return g.conn
+}
+
+// Env returns the environment a plugin needs to connect to the gateway
+func (g *Gateway) Env() []string {
+	env := []string{
+		"AGENT_URI=" + g.URL(),
+		"PLUGIN_ID=" + g.opts.PluginID,
+		"AGENT_CRED=",
+	}
+	if g.opts.EventChannel != "" {
+		env = append(env, "SOREN_EVENT_CHANNEL="+g.opts.EventChannel)
+	}
+	return env
+}
+
+// Close disconnects the gateway and stops the NATS server
+func (g *Gateway) Close() {
+	if g.conn != nil {
+		g.conn.Close()
+	}
+	if g.server != nil {
+		g.server.Shutdown()
+		g.server.WaitForShutdown()
+	}
+	g.mutex.Lock()
+	for jobId, job := range g.jobs {
+		close(job.updates)
+		delete(g.jobs, jobId)
+	}
+	g.mutex.Unlock()
+	g.cleanup()
+}
+
+func (g *Gateway) cleanup() {
+	if g.storeDir != "" {
+		os.RemoveAll(g.storeDir)
+	}
+}
+
+// subjectID is the plugin ID as it appears in subjects, with the entity in place of the bin.* wildcard
+func (g *Gateway) subjectID() string {
+	return strings.Replace(g.opts.PluginID, "*", g.opts.EntityID, 1)
+}
+
+// WaitReady waits until the plugin answers @intro
+func (g *Gateway) WaitReady(ctx context.Context) (models.PluginIntro, error) {
+	for {
+		intro, err := g.Intro()
+		if err == nil {
+			return intro, nil
+		}
+		select {
+		case <-ctx.Done():
+			return intro, fmt.Errorf("plugin is not ready: %w", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// request sends body to subject and returns the raw reply
+func (g *Gateway) request(subject string, body []byte) ([]byte, error) {
+	msg, err := g.conn.Request(subject, body, g.opts.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", subject, err)
+	}
+	return msg.Data, nil
+}
+
+// Raw sends a request to a plugin subject, given without the soren.v2.<plugin> prefix, and returns the raw reply
+func (g *Gateway) Raw(subject string, body []byte) ([]byte, error) {
+	return g.request(fmt.Sprintf("soren.v2.%s.%s", g.subjectID(), subject), body)
+}
+
+// Intro fetches @intro
+func (g *Gateway) Intro() (models.PluginIntro, error) {
+	intro := models.PluginIntro{}
+	data, err := g.Raw("@intro", nil)
+	if err != nil {
+		return intro, err
+	}
+	return intro, sonic.Unmarshal(data, &intro)
+}
+
+// Settings fetches @settings, nil when the plugin has none
+func (g *Gateway) Settings() (*models.Settings, error) {
+	data, err := g.Raw("@settings", nil)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	settings := &models.Settings{}
+	return settings, sonic.Unmarshal(data, settings)
+}
+
+// Actions fetches @actions
+func (g *Gateway) Actions() ([]models.Action, error) {
+	actions := []models.Action{}
+	data, err := g.Raw("@actions", nil)
+	if err != nil {
+		return nil, err
+	}
+	return actions, sonic.Unmarshal(data, &actions)
+}
+
+// Form fetches the form of an action
+func (g *Gateway) Form(method string) (models.ActionFormBuilder, error) {
+	form := models.ActionFormBuilder{}
+	data, err := g.Raw(method+".@form", nil)
+	if err != nil {
+		return form, err
+	}
+	return form, sonic.Unmarshal(data, &form)
+}
+
+// Submit sends a settings or requirements submission to replyTo
+func (g *Gateway) Submit(replyTo string, data map[string]any) ([]byte, error) {
+	body, err := sonic.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return g.Raw(replyTo, body)
+}
+
+// Invoke sends an action request to the plugin, as the gateway does on soren.cpu.<plugin>.<method>
+func (g *Gateway) Invoke(method string, body map[string]any, registry map[string]any) (*Invocation, error) {
+	if registry == nil {
+		registry = map[string]any{}
+	}
+	reqByte, err := sonic.Marshal(models.ActionRequestContent{Registry: registry, Body: body})
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.request(fmt.Sprintf("soren.cpu.%s.%s", g.subjectID(), method), reqByte)
+	if err != nil {
+		return nil, err
+	}
+	inv := &Invocation{}
+	if err := sonic.Unmarshal(data, &inv.Reply); err != nil {
+		return nil, fmt.Errorf("invalid reply %s: %w", string(data), err)
+	}
+	if inv.Reply.JobId == "" {
+		return inv, nil
+	}
+	inv.Updates = g.track(inv.Reply.JobId)
+	return inv, nil
+}
+
+// track registers a job and replays the updates that arrived before its accept reply
+func (g *Gateway) track(jobId string) <-chan Update {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	// the replayed updates must fit, the lock is held while they are sent
+	updates := make(chan Update, max(64, len(g.pending[jobId])))
+	done := false
+	for _, u := range g.pending[jobId] {
+		updates <- u
+		done = done || u.Done()
+	}
+	delete(g.pending, jobId)
+	if done {
+		close(updates)
+	} else {
+		g.jobs[jobId] = &trackedJob{updates: updates}
+	}
+	return updates
+}
+
+// trackedJob is a job whose updates go to an Invocation
+type trackedJob struct {
+	updates chan Update
+	dropped int
+}
+
+// send delivers an update without blocking the gateway on a reader that fell behind.
+// Progress updates are dropped when the channel is full, the final update makes room
+// by dropping the oldest one so the reader always sees how the job ended.
+func (j *trackedJob) send(update Update) {
+	for {
+		update.Dropped = j.dropped
+		select {
+		case j.updates <- update:
+			return
+		default:
+		}
+		if !update.Done() {
+			j.dropped++
+			return
+		}
+		select {
+		case <-j.updates:
+			j.dropped++
+		default:
+		}
+	}
+}
+
+func (g *Gateway) handleJobCommand(msg *nats.Msg) {
+	rest := strings.TrimPrefix(msg.Subject, fmt.Sprintf("soren.cpu.%s.", g.subjectID()))
+	jobId, command, ok := strings.Cut(rest, ".")
+	if !ok {
+		return
+	}
+	update := Update{JobID: jobId, Command: command, Received: time.Now()}
+	if err := sonic.Unmarshal(msg.Data, &update.Progress); err != nil {
+		msg.Respond([]byte(`{"result":"invalid progress"}`))
+		return
+	}
+	msg.Respond([]byte(`{"result":"OK"}`))
+	if g.opts.OnUpdate != nil {
+		g.opts.OnUpdate(update)
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	job, ok := g.jobs[jobId]
+	if !ok {
+		g.expirePending(update.Received)
+		g.pending[jobId] = append(g.pending[jobId], update)
+		return
+	}
+	job.send(update)
+	if update.Done() {
+		close(job.updates)
+		delete(g.jobs, jobId)
+	}
+}
+
+// expirePending drops the updates of jobs that were not tracked within pendingTTL, e.g. jobs
+// started by another client. The caller holds g.mutex.
+func (g *Gateway) expirePending(now time.Time) {
+	for jobId, updates := range g.pending {
+		if now.Sub(updates[0].Received) > pendingTTL {
+			delete(g.pending, jobId)
+		}
+	}
+}
+
+func (g *Gateway) handleEvents(msg *nats.Msg) {
+	events := []models.PluginEvent{}
+	if err := json.Unmarshal(msg.Data, &events); err != nil {
+		msg.Respond([]byte(`{"result":"invalid events"}`))
+		return
+	}
+	msg.Respond([]byte(`{"result":"OK"}`))
+	g.mutex.Lock()
+	g.events = append(g.events, events...)
+	g.mutex.Unlock()
+	if g.opts.OnEvent != nil {
+		for _, event := range events {
+			g.opts.OnEvent(event)
+		}
+	}
+}
+
+// Events returns the events captured so far
+func (g *Gateway) Events() []models.PluginEvent {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return append([]models.PluginEvent(nil), g.events...)
+}
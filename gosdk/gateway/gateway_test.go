@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestTrackManyPending(t *testing.T) {
+	g := &Gateway{jobs: map[string]*trackedJob{}, pending: map[string][]Update{}}
+	for i := range 100 {
+		g.pending["job"] = append(g.pending["job"], Update{JobID: "job", Command: string(models.ProgressCommand), Progress: models.JobProgress{Progress: i}})
+	}
+	updates := g.track("job")
+	if len(updates) != 100 {
+		t.Fatalf("replayed %d updates, want 100", len(updates))
+	}
+	if _, ok := g.pending["job"]; ok {
+		t.Error("pending updates kept after tracking")
+	}
+}
+
+func TestExpirePending(t *testing.T) {
+	now := time.Now()
+	g := &Gateway{pending: map[string][]Update{
+		"stale": {{JobID: "stale", Received: now.Add(-2 * pendingTTL)}},
+		"fresh": {{JobID: "fresh", Received: now.Add(-time.Second)}},
+	}}
+	g.expirePending(now)
+	if _, ok := g.pending["stale"]; ok {
+		t.Error("stale updates kept")
+	}
+	if _, ok := g.pending["fresh"]; !ok {
+		t.Error("fresh updates dropped")
+	}
+}
+
+func TestUpdateDone(t *testing.T) {
+	tests := []struct {
+		name   string
+		update Update
+		done   bool
+	}{
+		{"progress", Update{Command: string(models.ProgressCommand), Progress: models.JobProgress{Progress: 50}}, false},
+		{"done", Update{Command: string(models.ProgressCommand), Progress: models.JobProgress{Progress: 100}}, true},
+		{"failed", Update{Command: string(models.ProgressCommand), Progress: models.JobProgress{Progress: 100, Details: map[string]any{"error": "broken"}}}, true},
+		{"stopped", Update{Command: string(models.StopCommand)}, true},
+		{"context", Update{Command: string(models.ContextCurrentCommand), Progress: models.JobProgress{Progress: 100}}, false},
+	}
+	for _, tt := range tests {
+		if done := tt.update.Done(); done != tt.done {
+			t.Errorf("%s: Done() = %v, want %v", tt.name, done, tt.done)
+		}
+	}
+}
+
+func TestSendKeepsFinalUpdate(t *testing.T) {
+	job := &trackedJob{updates: make(chan Update, 2)}
+	for i := range 5 {
+		job.send(Update{Command: string(models.ProgressCommand), Progress: models.JobProgress{Progress: i * 10}})
+	}
+	job.send(Update{Command: string(models.ProgressCommand), Progress: models.JobProgress{Progress: 100}})
+	close(job.updates)
+	got := []Update{}
+	for u := range job.updates {
+		got = append(got, u)
+	}
+	if len(got) != 2 || got[0].Progress.Progress != 10 || !got[1].Done() {
+		t.Fatalf("updates %+v, want progress 10 and the final one", got)
+	}
+	if got[1].Dropped != 4 {
+		t.Errorf("final update counts %d dropped, want 4", got[1].Dropped)
+	}
+}
@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+)
+
+// ReplayOptions configures Replay
+type ReplayOptions struct {
+	Realtime bool // keeps the recorded gaps between requests
+	// IgnoreFields are JSON keys left out of the reply comparison at any depth, e.g. timestamps.
+	// UUIDs such as jobIds never compare as they are generated per run.
+	IgnoreFields []string
+}
+
+// Mismatch is a reply that differs from the recording
+type Mismatch struct {
+	ID       uint64
+	Subject  string
+	Expected []byte
+	Got      []byte
+	Reason   string
+}
+
+// ReplayReport is the outcome of a Replay
+type ReplayReport struct {
+	Requests   int
+	Mismatches []Mismatch
+}
+
+// Err returns an error describing the mismatches, nil when every reply matched
+func (r *ReplayReport) Err() error {
+	if len(r.Mismatches) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d of %d replies differ from the recording", len(r.Mismatches), r.Requests)
+	for _, m := range r.Mismatches {
+		fmt.Fprintf(&buf, "\n  #%d %s: %s\n    expected: %s\n    got:      %s", m.ID, m.Subject, m.Reason, m.Expected, m.Got)
+	}
+	return fmt.Errorf("%s", buf.String())
+}
+
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// Replay sends the inbound requests of a recording, as read by sdkv2.ReadRecords, to the plugin
+// connected to the gateway and compares its replies with the recorded ones.
+// Requests that were not replied to in the recording are only published.
+func (g *Gateway) Replay(records []sdkv2.Record, opts ReplayOptions) (*ReplayReport, error) {
+	replies := map[uint64]sdkv2.Record{}
+	for _, record := range records {
+		if record.Direction == sdkv2.RecordReply {
+			replies[record.ID] = record
+		}
+	}
+	report := &ReplayReport{}
+	var last time.Time
+	for _, record := range records {
+		if record.Direction != sdkv2.RecordIn {
+			continue
+		}
+		if opts.Realtime && !last.IsZero() {
+			time.Sleep(record.Time.Sub(last))
+		}
+		last = record.Time
+		msg := &nats.Msg{Subject: record.Subject, Header: record.Header, Data: record.Payload()}
+		expected, replied := replies[record.ID]
+		if !replied {
+			if err := g.conn.PublishMsg(msg); err != nil {
+				return report, err
+			}
+			continue
+		}
+		report.Requests++
+		resp, err := g.conn.RequestMsg(msg, g.opts.Timeout)
+		if err != nil {
+			report.Mismatches = append(report.Mismatches, Mismatch{
+				ID: record.ID, Subject: record.Subject, Expected: expected.Payload(), Reason: err.Error(),
+			})
+			continue
+		}
+		if reason := compareReply(expected.Payload(), resp.Data, opts.IgnoreFields); reason != "" {
+			report.Mismatches = append(report.Mismatches, Mismatch{
+				ID: record.ID, Subject: record.Subject, Expected: expected.Payload(), Got: resp.Data, Reason: reason,
+			})
+		}
+	}
+	return report, nil
+}
+
+// compareReply returns why got differs from expected, empty when they match
+func compareReply(expected, got []byte, ignore []string) string {
+	expected = uuidPattern.ReplaceAll(expected, []byte("<uuid>"))
+	got = uuidPattern.ReplaceAll(got, []byte("<uuid>"))
+	var want, have any
+	if json.Unmarshal(expected, &want) != nil || json.Unmarshal(got, &have) != nil {
+		if bytes.Equal(expected, got) {
+			return ""
+		}
+		return "payload differs"
+	}
+	skip := map[string]bool{}
+	for _, field := range ignore {
+		skip[field] = true
+	}
+	if !reflect.DeepEqual(dropFields(want, skip), dropFields(have, skip)) {
+		return "reply differs"
+	}
+	return ""
+}
+
+func dropFields(v any, skip map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, value := range v {
+			if skip[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = dropFields(value, skip)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = dropFields(value, skip)
+		}
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func startPlugin(t *testing.T, g *Gateway, record string, greeting string) *sdkv2.SorenSDK {
+	t.Helper()
+	sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: g.URL(), PluginID: g.opts.PluginID, RecordFile: record})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := sdkv2.NewPlugin(sdk)
+	plugin.SetIntro(models.PluginIntro{Name: "Replay", Version: "1.0.0"}, nil)
+	plugin.AddActions([]models.Action{{Method: "greet", Title: "Greet"}})
+	plugin.Handle("greet", func(req *sdkv2.Request) {
+		req.Accept()
+		req.Job().Done(map[string]any{"greeting": greeting})
+	})
+	go plugin.Start()
+	if _, err := g.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return sdk
+}
+
+func TestReplay(t *testing.T) {
+	g, err := Start(Options{PluginID: "replay-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	record := filepath.Join(t.TempDir(), "record.jsonl")
+
+	sdk := startPlugin(t, g, record, "hello")
+	inv, err := g.Invoke("greet", map[string]any{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range inv.Updates {
+	}
+	sdk.Close()
+
+	records, err := sdkv2.ReadRecords(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directions := map[sdkv2.RecordDirection]int{}
+	for _, r := range records {
+		directions[r.Direction]++
+	}
+	if directions[sdkv2.RecordIn] < 2 || directions[sdkv2.RecordReply] != directions[sdkv2.RecordIn] || directions[sdkv2.RecordOut] == 0 {
+		t.Fatalf("unexpected records: %v", directions)
+	}
+
+	sdk = startPlugin(t, g, "", "hello")
+	report, err := g.Replay(records, ReplayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sdk.Close()
+
+	// a plugin whose intro changed no longer matches the recording
+	for i, r := range records {
+		if r.Direction == sdkv2.RecordReply && strings.Contains(string(r.Data), `"Replay"`) {
+			records[i].Data = []byte(strings.Replace(string(r.Data), `"Replay"`, `"Other"`, 1))
+		}
+	}
+	sdk = startPlugin(t, g, "", "hello")
+	defer sdk.Close()
+	report, err = g.Replay(records, ReplayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Mismatches) != 1 || !strings.HasSuffix(report.Mismatches[0].Subject, "@intro") {
+		t.Fatalf("expected the intro to mismatch, got %v", report.Mismatches)
+	}
+}
@@ -1,16 +1,24 @@
 package sdkv2
 
-import "sync"
+import (
+	"strings"
+	"sync"
+)
 
+// pluginsHolder is the process wide registry of plugins, kept in registration order
 type pluginsHolder struct {
 	holder map[string]*Plugin
-	mutex sync.RWMutex
+	order  []string
+	mutex  sync.RWMutex
 }
-var ph *pluginsHolder
+
+var (
+	ph     *pluginsHolder
+	phOnce sync.Once
+)
 
 func (d *pluginsHolder) get(key string) (*Plugin, bool) {
 	d.mutex.RLock()
-
 	defer d.mutex.RUnlock()
 	val, exists := d.holder[key]
 	return val, exists
@@ -19,14 +27,89 @@ func (d *pluginsHolder) get(key string) (*Plugin, bool) {
 func (d *pluginsHolder) add(key string, value *Plugin) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
+	if _, exists := d.holder[key]; !exists {
+		d.order = append(d.order, key)
+	}
 	d.holder[key] = value
 }
 
-func GetPluginHolder()*pluginsHolder{
-	if ph==nil{
-		ph = &pluginsHolder{}
-		ph.holder = make(map[string]*Plugin)
+func (d *pluginsHolder) remove(key string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.removeLocked(key)
+}
+
+// removeSDK removes the plugins created with sdk, a plugin registered again under the same ID by another SDK stays
+func (d *pluginsHolder) removeSDK(sdk *SorenSDK) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, k := range append([]string(nil), d.order...) {
+		if d.holder[k].sdk == sdk {
+			d.removeLocked(k)
+		}
+	}
+}
+
+// removeLocked removes key, the mutex must be held
+func (d *pluginsHolder) removeLocked(key string) {
+	if _, exists := d.holder[key]; !exists {
+		return
+	}
+	delete(d.holder, key)
+	for i, k := range d.order {
+		if k == key {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// first returns the earliest registered plugin
+func (d *pluginsHolder) first() (*Plugin, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if len(d.order) == 0 {
+		return nil, false
 	}
+	return d.holder[d.order[0]], true
+}
 
-	return  ph
-}
\ No newline at end of file
+// list returns the registered plugins in registration order
+func (d *pluginsHolder) list() []*Plugin {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	plugins := make([]*Plugin, 0, len(d.order))
+	for _, k := range d.order {
+		plugins = append(plugins, d.holder[k])
+	}
+	return plugins
+}
+
+// bySubject returns the plugin that owns the given NATS subject.
+// When the IDs of several plugins match, as scanner and scanner.v2 both match
+// soren.cpu.scanner.v2.scan, the longest one owns it.
+func (d *pluginsHolder) bySubject(subject string) (*Plugin, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	var owner *Plugin
+	longest := 0
+	for _, k := range d.order {
+		p := d.holder[k]
+		if _, ok := p.sdk.matchSubject(subject); !ok {
+			continue
+		}
+		if tokens := strings.Count(p.sdk.pluginID, ".") + 1; tokens > longest {
+			owner, longest = p, tokens
+		}
+	}
+	return owner, owner != nil
+}
+
+func GetPluginHolder() *pluginsHolder {
+	phOnce.Do(func() {
+		ph = &pluginsHolder{
+			holder: make(map[string]*Plugin),
+		}
+	})
+	return ph
+}
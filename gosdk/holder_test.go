@@ -0,0 +1,107 @@
+package sdkv2
+
+import (
+	"strings"
+	"testing"
+)
+
+const binUUID = "6f1c2a7e-0b9d-4c1e-9a55-3d2f8e41b7c0"
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		name     string
+		pluginID string
+		subject  string
+		ok       bool
+		prefix   string
+		entityID string
+		rest     string
+	}{
+		{"exact v2", "scanner", "soren.v2.scanner.@intro", true, "v2", "", "@intro"},
+		{"exact cpu", "scanner", "soren.cpu.scanner.scan", true, "cpu", "", "scan"},
+		{"job command", "scanner", "soren.cpu.scanner.job-1.progress", true, "cpu", "", "job-1.progress"},
+		{"wildcard", "bin.*." + binUUID, "soren.cpu.bin.space-1." + binUUID + ".scan", true, "cpu", "space-1", "scan"},
+		{"wildcard form", "bin.*." + binUUID, "soren.v2.bin.space-2." + binUUID + ".scan.@form", true, "v2", "space-2", "scan.@form"},
+		{"other plugin", "scanner", "soren.cpu.reporter.scan", false, "", "", ""},
+		{"plugin ID prefix", "scan", "soren.cpu.scanner.scan", false, "", "", ""},
+		{"other uuid", "bin.*." + binUUID, "soren.cpu.bin.space-1.00000000-0000-0000-0000-000000000000.scan", false, "", "", ""},
+		{"no entity", "bin.*." + binUUID, "soren.cpu.bin." + binUUID + ".scan", false, "", "", ""},
+		{"nothing after the plugin ID", "scanner", "soren.cpu.scanner", false, "", "", ""},
+		{"other prefix", "scanner", "soren.plugin.scanner.scan", false, "", "", ""},
+		{"not soren", "scanner", "nats.cpu.scanner.scan", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SorenSDK{pluginID: tt.pluginID}
+			parts, ok := s.matchSubject(tt.subject)
+			if ok != tt.ok {
+				t.Fatalf("matchSubject(%s) = %v, want %v", tt.subject, ok, tt.ok)
+			}
+			if parts.Prefix != tt.prefix || parts.EntityID != tt.entityID || strings.Join(parts.Rest, ".") != tt.rest {
+				t.Errorf("parts %+v", parts)
+			}
+		})
+	}
+}
+
+func TestBySubject(t *testing.T) {
+	holder := &pluginsHolder{holder: map[string]*Plugin{}}
+	for _, id := range []string{"scanner", "bin.*." + binUUID, "reporter", "scanner.v2"} {
+		holder.add(id, &Plugin{sdk: &SorenSDK{pluginID: id}})
+	}
+	tests := []struct {
+		subject string
+		want    string // plugin ID, none when empty
+	}{
+		{"soren.cpu.scanner.scan", "scanner"},
+		{"soren.cpu.scanner.v2.scan", "scanner.v2"}, // the longest ID, though scanner matches first
+		{"soren.cpu.scanner.v3.scan", "scanner"},
+		{"soren.v2.reporter.@intro", "reporter"},
+		{"soren.cpu.bin.space-1." + binUUID + ".scan", "bin.*." + binUUID},
+		{"soren.v2.bin.space-2." + binUUID + ".@actions", "bin.*." + binUUID},
+		{"soren.cpu.bin.space-1.other-uuid.scan", ""},
+		{"soren.cpu.unknown.scan", ""},
+		{"soren.cpu.scanner", ""},
+	}
+	for _, tt := range tests {
+		p, ok := holder.bySubject(tt.subject)
+		if ok != (tt.want != "") {
+			t.Errorf("%s: routed %v, want %q", tt.subject, ok, tt.want)
+			continue
+		}
+		if ok && p.ID() != tt.want {
+			t.Errorf("%s: routed to %s, want %s", tt.subject, p.ID(), tt.want)
+		}
+	}
+
+	holder.remove("scanner")
+	if _, ok := holder.bySubject("soren.cpu.scanner.scan"); ok {
+		t.Error("removed plugin still routed")
+	}
+	if first, _ := holder.first(); first.ID() != "bin.*."+binUUID {
+		t.Errorf("first plugin %s after removal", first.ID())
+	}
+}
+
+func TestRemoveSDK(t *testing.T) {
+	holder := &pluginsHolder{holder: map[string]*Plugin{}}
+	closed := &SorenSDK{pluginID: "scanner"}
+	other := &SorenSDK{pluginID: "reporter"}
+	holder.add("scanner", &Plugin{sdk: closed})
+	holder.add("reporter", &Plugin{sdk: other})
+	holder.removeSDK(closed)
+	if _, ok := holder.get("scanner"); ok {
+		t.Error("plugin of the closed SDK still registered")
+	}
+	if _, ok := holder.get("reporter"); !ok {
+		t.Error("plugin of another SDK removed")
+	}
+
+	// a plugin registered again under the ID by a newer SDK stays
+	newer := &SorenSDK{pluginID: "reporter"}
+	holder.add("reporter", &Plugin{sdk: newer})
+	holder.removeSDK(other)
+	if p, ok := holder.get("reporter"); !ok || p.sdk != newer {
+		t.Error("plugin of the newer SDK removed")
+	}
+}
@@ -0,0 +1,86 @@
+package sdkv2
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// DefaultIdempotencyWindow is how long an accepted request is remembered when Config.IdempotencyWindow is not set
+const DefaultIdempotencyWindow = 10 * time.Minute
+
+type acceptedJob struct {
+	jobId   string
+	expires time.Time
+}
+
+// acceptedJobs remembers which job an idempotency key started
+type acceptedJobs struct {
+	holder map[string]acceptedJob
+	window time.Duration
+	mutex  sync.Mutex
+}
+
+func newAcceptedJobs(window time.Duration) *acceptedJobs {
+	return &acceptedJobs{
+		holder: make(map[string]acceptedJob),
+		window: window,
+	}
+}
+
+func (d *acceptedJobs) Get(key string) (string, bool) {
+	if d.window <= 0 || key == "" {
+		return "", false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	job, exists := d.holder[key]
+	if !exists || time.Now().After(job.expires) {
+		return "", false
+	}
+	return job.jobId, true
+}
+
+// GetOrAdd returns the job already accepted for key, or remembers jobId for it.
+// Concurrent retries with the same key all get the job of the first one.
+func (d *acceptedJobs) GetOrAdd(key string, jobId string) (string, bool) {
+	if d.window <= 0 || key == "" {
+		return jobId, false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	now := time.Now()
+	if job, exists := d.holder[key]; exists && !now.After(job.expires) {
+		return job.jobId, true
+	}
+	for k, job := range d.holder {
+		if now.After(job.expires) {
+			delete(d.holder, k)
+		}
+	}
+	d.holder[key] = acceptedJob{jobId: jobId, expires: now.Add(d.window)}
+	return jobId, false
+}
+
+// idempotencyKey returns the key identifying retries of the same request.
+// It is read from the Idempotency-Key or Nats-Msg-Id header, then from _registry.idempotencyKey,
+// and scoped by the subject so equal keys of different actions or entities don't collide.
+func idempotencyKey(msg *nats.Msg) string {
+	key := msg.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = msg.Header.Get(nats.MsgIdHdr)
+	}
+	if key == "" && len(msg.Data) > 0 {
+		content := models.ActionRequestContent{}
+		if err := sonic.Unmarshal(msg.Data, &content); err == nil {
+			key, _ = content.Registry["idempotencyKey"].(string)
+		}
+	}
+	if key == "" {
+		return ""
+	}
+	return msg.Subject + "|" + key
+}
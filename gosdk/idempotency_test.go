@@ -0,0 +1,97 @@
+package sdkv2
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestAcceptedJobs(t *testing.T) {
+	jobs := newAcceptedJobs(50 * time.Millisecond)
+	if jobId, existed := jobs.GetOrAdd("soren.cpu.p.create|key-1", "job-1"); existed || jobId != "job-1" {
+		t.Fatalf("first key got %q %v, want job-1 added", jobId, existed)
+	}
+	if jobId, existed := jobs.GetOrAdd("soren.cpu.p.create|key-1", "job-2"); !existed || jobId != "job-1" {
+		t.Fatalf("duplicate key got %q %v, want the original job-1", jobId, existed)
+	}
+	if jobId, ok := jobs.Get("soren.cpu.p.create|key-1"); !ok || jobId != "job-1" {
+		t.Fatalf("get got %q %v, want job-1", jobId, ok)
+	}
+	if _, ok := jobs.Get("soren.cpu.p.create|key-2"); ok {
+		t.Error("another key matched")
+	}
+	if _, ok := jobs.Get(""); ok {
+		t.Error("a request without key matched")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := jobs.Get("soren.cpu.p.create|key-1"); ok {
+		t.Error("the key did not expire")
+	}
+
+	disabled := newAcceptedJobs(-1)
+	if jobId, existed := disabled.GetOrAdd("soren.cpu.p.create|key-1", "job-1"); existed || jobId != "job-1" {
+		t.Errorf("disabled window got %q %v", jobId, existed)
+	}
+	if _, ok := disabled.Get("soren.cpu.p.create|key-1"); ok {
+		t.Error("a negative window remembered the key")
+	}
+}
+
+func TestAcceptConcurrentDuplicates(t *testing.T) {
+	p := &Plugin{
+		sdk:      &SorenSDK{pluginID: "p", metrics: newSDKMetrics(), propagator: propagation.TraceContext{}},
+		accepted: newAcceptedJobs(time.Minute),
+	}
+	jobIds := make([]string, 64)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range jobIds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			msg := &nats.Msg{Subject: "soren.cpu.p.create", Header: nats.Header{}}
+			msg.Header.Set("Idempotency-Key", "key-1")
+			jobIds[i] = p.Accept(msg)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	for _, jobId := range jobIds {
+		if jobId == "" || jobId != jobIds[0] {
+			t.Fatalf("duplicates accepted as %v, want a single job", jobIds)
+		}
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	request := func(subject, header, value, data string) *nats.Msg {
+		msg := &nats.Msg{Subject: subject, Header: nats.Header{}, Data: []byte(data)}
+		if header != "" {
+			msg.Header.Set(header, value)
+		}
+		return msg
+	}
+	tests := []struct {
+		name string
+		msg  *nats.Msg
+		want string
+	}{
+		{"header", request("soren.cpu.p.create", "Idempotency-Key", "k", ""), "soren.cpu.p.create|k"},
+		{"message id", request("soren.cpu.p.create", nats.MsgIdHdr, "k", ""), "soren.cpu.p.create|k"},
+		{"registry", request("soren.cpu.p.create", "", "", `{"_registry": {"idempotencyKey": "k"}}`), "soren.cpu.p.create|k"},
+		{"header before registry", request("soren.cpu.p.create", "Idempotency-Key", "h", `{"_registry": {"idempotencyKey": "r"}}`), "soren.cpu.p.create|h"},
+		{"other action", request("soren.cpu.p.delete", "Idempotency-Key", "k", ""), "soren.cpu.p.delete|k"},
+		{"other caller", request("soren.cpu.bin.space-2.p.create", "Idempotency-Key", "k", ""), "soren.cpu.bin.space-2.p.create|k"},
+		{"no key", request("soren.cpu.p.create", "", "", `{"body": {}}`), ""},
+		{"invalid body", request("soren.cpu.p.create", "", "", `not json`), ""},
+	}
+	for _, tt := range tests {
+		if got := idempotencyKey(tt.msg); got != tt.want {
+			t.Errorf("%s: key %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package sdkv2
+
+import (
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Job is an accepted request whose progress is reported back to the requester
+type Job struct {
+	ID       string
+	EntityID string
+	Method   string
+	plugin   *Plugin
+
+	correlationID string
+	traceID       string
+	spanContext   trace.SpanContext
+}
+
+// Plugin returns the plugin running the job
+func (j *Job) Plugin() *Plugin {
+	return j.plugin
+}
+
+// Progress reports progress of the job
+func (j *Job) Progress(data models.JobProgress) any {
+	return j.plugin.Progress(j.ID, models.ProgressCommand, data)
+}
+
+// Command sends a job command other than progress, e.g. context/current
+func (j *Job) Command(command models.Command, data models.JobProgress) any {
+	return j.plugin.Progress(j.ID, command, data)
+}
+
+// Done reports the job as completed with its final result
+func (j *Job) Done(data map[string]any) any {
+	return j.plugin.Done(j.ID, data)
+}
+
+// Fail ends the job with err instead of a result
+func (j *Job) Fail(err error) any {
+	return j.plugin.Fail(j.ID, err)
+}
@@ -4,9 +4,13 @@ import "sync"
 
 type jobToReqMap struct {
 	holder map[string]string
-	mutex sync.RWMutex
+	mutex  sync.RWMutex
 }
-var sj *jobToReqMap // Space Jobs
+
+var (
+	sj     *jobToReqMap // Space Jobs
+	sjOnce sync.Once
+)
 
 func (d *jobToReqMap) Get(key string) (string, bool) {
 	d.mutex.RLock()
@@ -25,14 +29,13 @@ func (d *jobToReqMap) Add(jobId string, entityId string) {
 func (d *jobToReqMap) Delete(jobId string) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
-	delete(d.holder,jobId)
+	delete(d.holder, jobId)
 }
 
-func GetjobsHolder()*jobToReqMap{
-	if sj==nil{
+func GetjobsHolder() *jobToReqMap {
+	sjOnce.Do(func() {
 		sj = &jobToReqMap{}
 		sj.holder = make(map[string]string)
-	}
-
-	return  sj
-}
\ No newline at end of file
+	})
+	return sj
+}
@@ -0,0 +1,164 @@
+// Package jsonui builds JSON Forms UI schemas, the Jsonui of models.Settings,
+// models.Requirements and models.ActionFormBuilder, with typed builders instead of map literals.
+//
+//	ui := jsonui.VerticalLayout(
+//		jsonui.Control("project").WithLabel("Project"),
+//		jsonui.Control("branch").ShowIf(jsonui.NotEmpty("project")),
+//	).Map()
+package jsonui
+
+import "strings"
+
+// Type is the type of a UI schema element
+type Type string
+
+const (
+	TypeVerticalLayout   Type = "VerticalLayout"
+	TypeHorizontalLayout Type = "HorizontalLayout"
+	TypeGroup            Type = "Group"
+	TypeCategorization   Type = "Categorization"
+	TypeCategory         Type = "Category"
+	TypeControl          Type = "Control"
+	TypeLabel            Type = "Label"
+)
+
+// Element is a UI schema element, build it with the constructors of this package
+type Element struct {
+	Type     Type
+	Scope    string // controls only
+	Label    string // group and category label, control label override
+	Text     string // labels only
+	Options  map[string]any
+	Elements []*Element
+	Rule     *Rule
+}
+
+// VerticalLayout stacks elements vertically
+func VerticalLayout(elements ...*Element) *Element {
+	return &Element{Type: TypeVerticalLayout, Elements: elements}
+}
+
+// HorizontalLayout puts elements side by side
+func HorizontalLayout(elements ...*Element) *Element {
+	return &Element{Type: TypeHorizontalLayout, Elements: elements}
+}
+
+// Group is a vertical layout with a label
+func Group(label string, elements ...*Element) *Element {
+	return &Element{Type: TypeGroup, Label: label, Elements: elements}
+}
+
+// Categorization shows each category as a tab
+func Categorization(categories ...*Element) *Element {
+	return &Element{Type: TypeCategorization, Elements: categories}
+}
+
+// Category is a tab of a Categorization
+func Category(label string, elements ...*Element) *Element {
+	return &Element{Type: TypeCategory, Label: label, Elements: elements}
+}
+
+// Control renders the schema property at path, a dot separated property path like "repo.branch"
+// or a JSON pointer like "#/properties/repo"
+func Control(path string) *Element {
+	return &Element{Type: TypeControl, Scope: Scope(path)}
+}
+
+// Label renders static text
+func Label(text string) *Element {
+	return &Element{Type: TypeLabel, Text: text}
+}
+
+// Scope turns a dot separated property path into a JSON pointer, JSON pointers are kept as they are
+func Scope(path string) string {
+	if strings.HasPrefix(path, "#") {
+		return path
+	}
+	return "#/properties/" + strings.ReplaceAll(path, ".", "/properties/")
+}
+
+// WithLabel sets the label of a control, group or category
+func (e *Element) WithLabel(label string) *Element {
+	e.Label = label
+	return e
+}
+
+// WithOption sets a renderer option, e.g. "multi", "format" or "readonly"
+func (e *Element) WithOption(key string, value any) *Element {
+	if e.Options == nil {
+		e.Options = map[string]any{}
+	}
+	e.Options[key] = value
+	return e
+}
+
+// Multi renders a string control as a text area
+func (e *Element) Multi() *Element {
+	return e.WithOption("multi", true)
+}
+
+// ReadOnly disables editing of the control
+func (e *Element) ReadOnly() *Element {
+	return e.WithOption("readonly", true)
+}
+
+// Format sets the format option, e.g. "radio" for enums
+func (e *Element) Format(format string) *Element {
+	return e.WithOption("format", format)
+}
+
+// WithRule applies rule to the element
+func (e *Element) WithRule(rule *Rule) *Element {
+	e.Rule = rule
+	return e
+}
+
+// ShowIf shows the element only while condition holds
+func (e *Element) ShowIf(condition Condition) *Element {
+	return e.WithRule(&Rule{Effect: EffectShow, Condition: condition})
+}
+
+// HideIf hides the element while condition holds
+func (e *Element) HideIf(condition Condition) *Element {
+	return e.WithRule(&Rule{Effect: EffectHide, Condition: condition})
+}
+
+// EnableIf enables the element only while condition holds
+func (e *Element) EnableIf(condition Condition) *Element {
+	return e.WithRule(&Rule{Effect: EffectEnable, Condition: condition})
+}
+
+// DisableIf disables the element while condition holds
+func (e *Element) DisableIf(condition Condition) *Element {
+	return e.WithRule(&Rule{Effect: EffectDisable, Condition: condition})
+}
+
+// Map returns the UI schema as the map Jsonui fields take
+func (e *Element) Map() map[string]any {
+	m := map[string]any{"type": string(e.Type)}
+	if e.Scope != "" {
+		m["scope"] = e.Scope
+	}
+	if e.Label != "" {
+		m["label"] = e.Label
+	}
+	if e.Text != "" {
+		m["text"] = e.Text
+	}
+	if len(e.Options) > 0 {
+		m["options"] = e.Options
+	}
+	if e.Type != TypeControl && e.Type != TypeLabel {
+		elements := make([]any, 0, len(e.Elements))
+		for _, element := range e.Elements {
+			if element != nil {
+				elements = append(elements, element.Map())
+			}
+		}
+		m["elements"] = elements
+	}
+	if e.Rule != nil {
+		m["rule"] = e.Rule.Map()
+	}
+	return m
+}
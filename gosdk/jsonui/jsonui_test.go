@@ -0,0 +1,24 @@
+package jsonui
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	ui := VerticalLayout(
+		Label("Scan a repository"),
+		Group("Source",
+			Control("repo.name").WithLabel("Repository"),
+			Control("branch").Format("radio").ShowIf(And(NotEmpty("repo.name"), Equals("mode", "full"))),
+		),
+	).Map()
+	got, err := json.Marshal(ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"elements":[{"text":"Scan a repository","type":"Label"},{"elements":[{"label":"Repository","scope":"#/properties/repo/properties/name","type":"Control"},{"options":{"format":"radio"},"rule":{"condition":{"conditions":[{"failWhenUndefined":true,"schema":{"minLength":1},"scope":"#/properties/repo/properties/name"},{"schema":{"const":"full"},"scope":"#/properties/mode"}],"type":"AND"},"effect":"SHOW"},"scope":"#/properties/branch","type":"Control"}],"label":"Source","type":"Group"}],"type":"VerticalLayout"}`
+	if string(got) != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
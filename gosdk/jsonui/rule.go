@@ -0,0 +1,82 @@
+package jsonui
+
+// Effect is what a rule does to its element
+type Effect string
+
+const (
+	EffectShow    Effect = "SHOW"
+	EffectHide    Effect = "HIDE"
+	EffectEnable  Effect = "ENABLE"
+	EffectDisable Effect = "DISABLE"
+)
+
+// Rule shows, hides, enables or disables an element depending on the form data
+type Rule struct {
+	Effect    Effect
+	Condition Condition
+}
+
+// Map returns the rule as it appears in a UI schema
+func (r *Rule) Map() map[string]any {
+	return map[string]any{"effect": string(r.Effect), "condition": r.Condition.Map()}
+}
+
+// Condition is a rule condition, either a schema the data at Scope must validate against
+// or an AND/OR of other conditions
+type Condition struct {
+	Scope  string
+	Schema map[string]any
+	// FailWhenUndefined makes the condition fail while the data at Scope is not set
+	FailWhenUndefined bool
+
+	Type       string // "AND" or "OR" for composed conditions
+	Conditions []Condition
+}
+
+// Matches holds when the property at path validates against schema
+func Matches(path string, schema map[string]any) Condition {
+	return Condition{Scope: Scope(path), Schema: schema}
+}
+
+// Equals holds when the property at path is value
+func Equals(path string, value any) Condition {
+	return Matches(path, map[string]any{"const": value})
+}
+
+// OneOf holds when the property at path is any of values
+func OneOf(path string, values ...any) Condition {
+	return Matches(path, map[string]any{"enum": values})
+}
+
+// NotEmpty holds when the property at path is set to a non empty string
+func NotEmpty(path string) Condition {
+	condition := Matches(path, map[string]any{"minLength": 1})
+	condition.FailWhenUndefined = true
+	return condition
+}
+
+// And holds when all conditions hold
+func And(conditions ...Condition) Condition {
+	return Condition{Type: "AND", Conditions: conditions}
+}
+
+// Or holds when any of conditions holds
+func Or(conditions ...Condition) Condition {
+	return Condition{Type: "OR", Conditions: conditions}
+}
+
+// Map returns the condition as it appears in a rule
+func (c Condition) Map() map[string]any {
+	if c.Type != "" {
+		conditions := make([]any, 0, len(c.Conditions))
+		for _, condition := range c.Conditions {
+			conditions = append(conditions, condition.Map())
+		}
+		return map[string]any{"type": c.Type, "conditions": conditions}
+	}
+	m := map[string]any{"scope": c.Scope, "schema": c.Schema}
+	if c.FailWhenUndefined {
+		m["failWhenUndefined"] = true
+	}
+	return m
+}
@@ -0,0 +1,104 @@
+package sdkv2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	"go.uber.org/zap/zapcore"
+)
+
+// eventCore is a zap core sending log entries as log events
+type eventCore struct {
+	zapcore.LevelEnabler
+	logger *EventLogger
+	fields []zapcore.Field
+}
+
+// NewEventCore returns a zap core sending the entries level enables as log events through logger,
+// with their fields in the event details. String jobId, entityId, correlationId and traceId fields
+// also link the event to its job and request.
+// Use an asynchronous logger so that logging does not wait for the platform.
+func NewEventCore(logger *EventLogger, level zapcore.LevelEnabler) zapcore.Core {
+	return &eventCore{LevelEnabler: level, logger: logger}
+}
+
+func (c *eventCore) With(fields []zapcore.Field) zapcore.Core {
+	return &eventCore{
+		LevelEnabler: c.LevelEnabler,
+		logger:       c.logger,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *eventCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// entries of the event pipeline itself are not sent, a failing delivery would log forever
+	if c.Enabled(ent.Level) && !isEventsLogger(ent.LoggerName) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// isEventsLogger reports whether name is the event pipeline logger, its children
+// or it named under a named base logger, e.g. app.soren-sdk-events.spool
+func isEventsLogger(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if part == eventsLoggerName {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *eventCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	details := enc.Fields
+	if ent.Caller.Defined {
+		details["caller"] = ent.Caller.TrimmedPath()
+	}
+	if ent.Stack != "" {
+		details["stack"] = ent.Stack
+	}
+	source := c.logger.sdk.pluginID
+	if ent.LoggerName != "" {
+		source = fmt.Sprintf("%s - %s", source, ent.LoggerName)
+	}
+	event := models.PluginEvent{
+		Event:   models.EventTypeLog,
+		Level:   zapLevelToLogLevel(ent.Level),
+		Source:  source,
+		Message: ent.Message,
+		Details: details,
+		Time:    ent.Time,
+	}
+	event.JobID, _ = details["jobId"].(string)
+	event.EntityID, _ = details["entityId"].(string)
+	event.CorrelationID, _ = details["correlationId"].(string)
+	event.TraceID, _ = details["traceId"].(string)
+	return c.logger.send(event)
+}
+
+// Sync sends what an asynchronous logger buffered
+func (c *eventCore) Sync() error {
+	c.logger.Flush()
+	return nil
+}
+
+func zapLevelToLogLevel(level zapcore.Level) models.LogLevel {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return models.LogLevelDebug
+	case level == zapcore.InfoLevel:
+		return models.LogLevelInfo
+	case level == zapcore.WarnLevel:
+		return models.LogLevelWarn
+	default:
+		return models.LogLevelError
+	}
+}
@@ -0,0 +1,61 @@
+package sdkv2_test
+
+import (
+	"testing"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	"github.com/sorenhq/go-plugin-sdk/logtool"
+)
+
+func TestLogEvents(t *testing.T) {
+	const channel = "soren.plugin.event.test"
+	gw, err := gateway.Start(gateway.Options{PluginID: "log-events", EventChannel: channel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	sdk, err := sdkv2.New(&sdkv2.Config{
+		AgentURI:         gw.URL(),
+		PluginID:         "log-events",
+		EventChannel:     channel,
+		LogEventLevel:    "warn",
+		DisableJobEvents: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdkv2.NewPlugin(sdk) // initializes logtool, the core stays
+
+	logger := logtool.GetLogger().With("jobId", "job-1")
+	logger.Info("cloning")
+	logger.Warnw("disk almost full", "free", 3)
+	logger.Named("scan").Errorw("scan failed", "repo", "sdk")
+	// the event pipeline logs are not sent back to it
+	logtool.GetLogger().Named("soren-sdk-events").Error("event batch error")
+	logtool.GetLogger().Named("app").Named("soren-sdk-events").Error("event batch error under a named base")
+	logtool.GetLogger().Named("soren-sdk-events").Named("spool").Error("event spool error")
+	sdk.Close()
+	logtool.GetLogger().Warn("after close")
+
+	got := []models.PluginEvent{}
+	for _, event := range gw.Events() {
+		if event.Event == models.EventTypeLog && event.Source != "log-events - soren-sdk-init" {
+			got = append(got, event)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d log events, want 2: %+v", len(got), got)
+	}
+	warn, failed := got[0], got[1]
+	if warn.Level != models.LogLevelWarn || warn.Message != "disk almost full" || warn.JobID != "job-1" {
+		t.Errorf("warn event: %+v", warn)
+	}
+	if warn.Details["free"] != float64(3) || warn.Details["jobId"] != "job-1" || warn.Details["caller"] == nil {
+		t.Errorf("warn details: %v", warn.Details)
+	}
+	if failed.Level != models.LogLevelError || failed.Source != "log-events - scan" || failed.Details["repo"] != "sdk" {
+		t.Errorf("error event: %+v", failed)
+	}
+}
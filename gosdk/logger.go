@@ -0,0 +1,89 @@
+package sdkv2
+
+import (
+	"context"
+
+	"github.com/sorenhq/go-plugin-sdk/logtool"
+	"go.uber.org/zap"
+)
+
+// The SDK logs through these loggers. The event pipeline logs through eventsLogger, whose entries
+// NewEventCore does not send, since its errors would otherwise loop back to it.
+
+// eventsLoggerName names the logger of the event pipeline
+const eventsLoggerName = "soren-sdk-events"
+
+type loggerKey struct{}
+
+// baseLogger is the logtool logger, a no-op one before logtool is initialized
+func baseLogger() *zap.SugaredLogger {
+	if logger := logtool.GetLogger(); logger != nil {
+		return logger
+	}
+	return zap.NewNop().Sugar()
+}
+
+// eventsLogger is the logger of the event pipeline
+func eventsLogger() *zap.SugaredLogger {
+	return baseLogger().Named(eventsLoggerName)
+}
+
+// LoggerFrom returns the logger of the request or job ctx comes from, see Request.Context and
+// Job.Context, the logtool logger otherwise
+func LoggerFrom(ctx context.Context) *zap.SugaredLogger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+			return logger
+		}
+	}
+	return baseLogger()
+}
+
+func withLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the logtool logger with the pluginId field
+func (p *Plugin) Logger() *zap.SugaredLogger {
+	return baseLogger().With("pluginId", p.ID())
+}
+
+// jobLogger returns the logger of a job, with the entity and method it was accepted with
+func (p *Plugin) jobLogger(jobId string) *zap.SugaredLogger {
+	fields := []any{"jobId", jobId}
+	if entityId, ok := GetjobsHolder().Get(jobId); ok {
+		fields = append(fields, "entityId", entityId)
+	}
+	if method, ok := p.jobMethods.Load(jobId); ok {
+		fields = append(fields, "method", method)
+	}
+	return p.Logger().With(fields...)
+}
+
+// Logger returns the logtool logger with the pluginId, entityId, method, correlationId and traceId
+// fields of the request, and jobId once it is accepted. With Config.LogEventLevel its entries become
+// events linked to the request.
+func (r *Request) Logger() *zap.SugaredLogger {
+	fields := append([]any{"method", r.Method}, r.scope().fields()...)
+	return r.plugin.Logger().With(fields...)
+}
+
+// Logger returns the logtool logger with the pluginId, entityId, jobId, method, correlationId and
+// traceId fields of the job
+func (j *Job) Logger() *zap.SugaredLogger {
+	fields := append([]any{"method", j.Method}, j.scope().fields()...)
+	return j.plugin.Logger().With(fields...)
+}
+
+// fields returns the IDs of the scope that are set as logger fields, named as the event core reads them
+func (s eventScope) fields() []any {
+	fields := []any{}
+	for _, f := range []struct{ key, value string }{
+		{"jobId", s.JobID}, {"entityId", s.EntityID}, {"correlationId", s.CorrelationID}, {"traceId", s.TraceID},
+	} {
+		if f.value != "" {
+			fields = append(fields, f.key, f.value)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,75 @@
+package sdkv2_test
+
+import (
+	"context"
+	"testing"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	"github.com/sorenhq/go-plugin-sdk/logtool"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestContextLoggers(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logtool.AddCore(core)
+	defer logtool.RemoveCore(core)
+
+	gw, err := gateway.Start(gateway.Options{PluginID: "bin.*.loggers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: gw.URL(), PluginID: "bin.*.loggers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.Close()
+	plugin := sdkv2.NewPlugin(sdk)
+	plugin.SetIntro(models.PluginIntro{Name: "Loggers", Version: "1.0.0"}, nil)
+	plugin.AddActions([]models.Action{{Method: "run", Title: "Run"}})
+	handled := make(chan struct{})
+	plugin.Handle("run", func(req *sdkv2.Request) {
+		defer close(handled)
+		req.Logger().Info("handling")
+		req.Accept()
+		sdkv2.LoggerFrom(req.Job().Context()).Info("working")
+		req.Job().Done(nil)
+	})
+	go plugin.Start()
+	if _, err := gw.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := gw.Invoke("run", map[string]any{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range inv.Updates {
+	}
+	<-handled // Done logs the result of the progress report
+	jobId := inv.Reply.JobId
+
+	tests := []struct {
+		message string
+		fields  map[string]any
+	}{
+		{"handling", map[string]any{"pluginId": "bin.*.loggers", "entityId": "local", "method": "run"}},
+		{"working", map[string]any{"pluginId": "bin.*.loggers", "entityId": "local", "method": "run", "jobId": jobId}},
+		{"progress command result", map[string]any{"pluginId": "bin.*.loggers", "entityId": "local", "method": "run", "jobId": jobId}},
+	}
+	for _, tt := range tests {
+		entries := logs.FilterMessage(tt.message).All()
+		if len(entries) == 0 {
+			t.Errorf("no %q log", tt.message)
+			continue
+		}
+		fields := entries[0].ContextMap()
+		for key, want := range tt.fields {
+			if fields[key] != want {
+				t.Errorf("%q log %s = %v, want %v", tt.message, key, fields[key], want)
+			}
+		}
+	}
+}
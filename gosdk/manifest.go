@@ -0,0 +1,141 @@
+package sdkv2
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFiles are the file names looked up when no manifest path is given
+var ManifestFiles = []string{"soren-plugin.yaml", "soren-plugin.yml", "soren-plugin.json"}
+
+// Manifest declares a plugin intro, settings and actions with their forms
+type Manifest struct {
+	Intro    models.PluginIntro `json:"intro"`
+	Settings *models.Settings   `json:"settings,omitempty"`
+	Actions  []models.Action    `json:"actions"`
+}
+
+// LoadManifest reads a YAML or JSON manifest file.
+// With an empty path the ManifestFiles are looked up in the working directory.
+func LoadManifest(path string) (*Manifest, error) {
+	if path == "" {
+		for _, name := range ManifestFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("no manifest found, looked for %s", strings.Join(ManifestFiles, ", "))
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	return ParseManifest(data, ext == ".yaml" || ext == ".yml")
+}
+
+// ParseManifest decodes a manifest, field names are the ones of the JSON encoding in both formats.
+// Unknown fields are ignored so that manifests written for a newer SDK still load.
+func ParseManifest(data []byte, isYAML bool) (*Manifest, error) {
+	if isYAML {
+		// go through JSON so the models json tags apply to YAML as well
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		jsonByte, err := sonic.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		data = jsonByte
+	}
+	manifest := &Manifest{}
+	if err := sonic.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if strings.TrimSpace(manifest.Intro.Name) == "" {
+		return nil, fmt.Errorf("invalid manifest: intro.name is required")
+	}
+	seen := map[string]bool{}
+	for i, action := range manifest.Actions {
+		if strings.TrimSpace(action.Method) == "" {
+			return nil, fmt.Errorf("invalid manifest: actions[%d].method is required", i)
+		}
+		if seen[action.Method] {
+			return nil, fmt.Errorf("invalid manifest: action %s is declared twice", action.Method)
+		}
+		seen[action.Method] = true
+	}
+	return manifest, nil
+}
+
+// UseManifest sets the plugin intro, settings and actions from a manifest.
+// Handlers are bound by method name with Handle, HandleSettings and HandleRequirements,
+// Start fails when a declaration has no handler or a handler has no declaration.
+func (p *Plugin) UseManifest(manifest *Manifest) {
+	p.manifest = manifest
+	p.Intro = manifest.Intro
+	p.Settings = manifest.Settings
+	p.Actions = manifest.Actions
+}
+
+// LoadManifest loads a manifest file with LoadManifest and uses it for the plugin
+func (p *Plugin) LoadManifest(path string) error {
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		return err
+	}
+	p.UseManifest(manifest)
+	return nil
+}
+
+// HandleSettings binds the settings submission handler
+func (p *Plugin) HandleSettings(handler SubmitHandler) {
+	p.settingsHandler = handler
+}
+
+// HandleRequirements binds the requirements submission handler
+func (p *Plugin) HandleRequirements(handler SubmitHandler) {
+	p.requirementsHandler = handler
+}
+
+// checkBindings reports declarations without handlers and handlers without declarations
+func (p *Plugin) checkBindings() error {
+	problems := []error{}
+	declared := map[string]bool{}
+	for _, action := range p.Actions {
+		declared[action.Method] = true
+		if p.actionHandler(action) == nil {
+			problems = append(problems, fmt.Errorf("action %s has no handler", action.Method))
+		}
+	}
+	for method := range p.handlers {
+		if !declared[method] {
+			problems = append(problems, fmt.Errorf("handler %s has no declared action", method))
+		}
+	}
+	if p.Settings != nil && p.settingsHandler == nil && p.Settings.Handler == nil {
+		problems = append(problems, fmt.Errorf("settings have no handler"))
+	}
+	if p.Settings == nil && p.settingsHandler != nil {
+		problems = append(problems, fmt.Errorf("settings handler has no declared settings"))
+	}
+	requirements := p.Intro.Requirements
+	if requirements != nil && strings.TrimSpace(requirements.ReplyTo) != "" && p.requirementsHandler == nil && requirements.Handler == nil {
+		problems = append(problems, fmt.Errorf("requirements have no handler"))
+	}
+	if requirements == nil && p.requirementsHandler != nil {
+		problems = append(problems, fmt.Errorf("requirements handler has no declared requirements"))
+	}
+	return errors.Join(problems...)
+}
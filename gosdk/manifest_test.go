@@ -0,0 +1,248 @@
+package sdkv2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		isYAML  bool
+		err     string // part of the error, none when empty
+		actions []string
+	}{
+		{
+			name:   "yaml",
+			isYAML: true,
+			data: `
+intro:
+  name: Scanner
+  version: 1.0.0
+  requirements:
+    replyTo: soren.v2.scanner.@requirements
+actions:
+  - method: scan
+    title: Scan
+    form:
+      jsonschema:
+        type: object
+  - method: report
+    title: Report
+`,
+			actions: []string{"scan", "report"},
+		},
+		{
+			name:    "json",
+			data:    `{"intro": {"name": "Scanner", "version": "1.0.0"}, "actions": [{"method": "scan", "title": "Scan"}]}`,
+			actions: []string{"scan"},
+		},
+		{
+			name:    "unknown fields are ignored",
+			isYAML:  true,
+			data:    "intro:\n  name: Scanner\n  homepage: https://example.com\nextra: true\nactions:\n  - method: scan\n    color: red\n",
+			actions: []string{"scan"},
+		},
+		{
+			name: "json read as yaml",
+			// JSON is YAML, the JSON field names apply to both
+			isYAML:  true,
+			data:    `{"intro": {"name": "Scanner"}, "actions": [{"method": "scan"}]}`,
+			actions: []string{"scan"},
+		},
+		{
+			name:   "yaml read as json",
+			data:   "intro:\n  name: Scanner\n",
+			err:    "invalid manifest",
+			isYAML: false,
+		},
+		{
+			name:   "invalid yaml",
+			isYAML: true,
+			data:   "intro: [name",
+			err:    "invalid manifest",
+		},
+		{
+			name: "missing name",
+			data: `{"intro": {"version": "1.0.0"}}`,
+			err:  "intro.name is required",
+		},
+		{
+			name: "missing method",
+			data: `{"intro": {"name": "Scanner"}, "actions": [{"title": "Scan"}]}`,
+			err:  "actions[0].method is required",
+		},
+		{
+			name: "duplicate method",
+			data: `{"intro": {"name": "Scanner"}, "actions": [{"method": "scan"}, {"method": "scan"}]}`,
+			err:  "action scan is declared twice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := ParseManifest([]byte(tt.data), tt.isYAML)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if manifest.Intro.Name != "Scanner" {
+				t.Errorf("intro %+v", manifest.Intro)
+			}
+			methods := []string{}
+			for _, action := range manifest.Actions {
+				methods = append(methods, action.Method)
+			}
+			if strings.Join(methods, ",") != strings.Join(tt.actions, ",") {
+				t.Errorf("actions %v, want %v", methods, tt.actions)
+			}
+		})
+	}
+}
+
+func TestParseManifestForms(t *testing.T) {
+	manifest, err := ParseManifest([]byte(`
+intro:
+  name: Scanner
+  requirements:
+    replyTo: soren.v2.scanner.@requirements
+settings:
+  replyTo: soren.v2.scanner.@settings
+  jsonschema:
+    type: object
+actions:
+  - method: scan
+    form:
+      jsonschema:
+        properties:
+          depth:
+            type: integer
+`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Intro.Requirements == nil || manifest.Intro.Requirements.ReplyTo != "soren.v2.scanner.@requirements" {
+		t.Errorf("requirements %+v", manifest.Intro.Requirements)
+	}
+	if manifest.Settings == nil || manifest.Settings.Jsonschema["type"] != "object" {
+		t.Errorf("settings %+v", manifest.Settings)
+	}
+	depth, _ := manifest.Actions[0].Form.Jsonschema["properties"].(map[string]any)["depth"].(map[string]any)
+	if depth["type"] != "integer" {
+		t.Errorf("action schema %v", manifest.Actions[0].Form.Jsonschema)
+	}
+}
+
+func TestCheckBindings(t *testing.T) {
+	handler := func(req *Request) {}
+	submit := func(req *Request) any { return nil }
+	tests := []struct {
+		name     string
+		manifest Manifest
+		handlers []string
+		settings bool // binds a settings handler
+		require  bool // binds a requirements handler
+		problems []string
+	}{
+		{
+			name:     "all bound",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}, Actions: []models.Action{{Method: "scan"}}},
+			handlers: []string{"scan"},
+		},
+		{
+			name:     "action without handler",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}, Actions: []models.Action{{Method: "scan"}, {Method: "report"}}},
+			handlers: []string{"scan"},
+			problems: []string{"action report has no handler"},
+		},
+		{
+			name:     "handler without action",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}, Actions: []models.Action{{Method: "scan"}}},
+			handlers: []string{"scan", "delete"},
+			problems: []string{"handler delete has no declared action"},
+		},
+		{
+			name:     "bound by request handler",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}, Actions: []models.Action{{Method: "scan", RequestHandler: func(msg *nats.Msg) {}}}},
+		},
+		{
+			name:     "no actions declared",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}},
+			handlers: []string{"scan"},
+			problems: []string{"handler scan has no declared action"},
+		},
+		{
+			name:     "every problem reported",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}, Actions: []models.Action{{Method: "scan"}}, Settings: &models.Settings{ReplyTo: "s"}},
+			handlers: []string{"delete"},
+			problems: []string{"action scan has no handler", "handler delete has no declared action", "settings have no handler"},
+		},
+		{
+			name:     "settings without handler",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}, Settings: &models.Settings{ReplyTo: "s"}},
+			problems: []string{"settings have no handler"},
+		},
+		{
+			name:     "settings handler without settings",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}},
+			settings: true,
+			problems: []string{"settings handler has no declared settings"},
+		},
+		{
+			name:     "requirements without handler",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p", Requirements: &models.Requirements{ReplyTo: "r"}}},
+			problems: []string{"requirements have no handler"},
+		},
+		{
+			name:     "requirements handler without requirements",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p"}},
+			require:  true,
+			problems: []string{"requirements handler has no declared requirements"},
+		},
+		{
+			name:     "settings and requirements bound",
+			manifest: Manifest{Intro: models.PluginIntro{Name: "p", Requirements: &models.Requirements{ReplyTo: "r"}}, Settings: &models.Settings{ReplyTo: "s"}},
+			settings: true,
+			require:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{handlers: map[string]HandlerFunc{}}
+			manifest := tt.manifest
+			p.UseManifest(&manifest)
+			for _, method := range tt.handlers {
+				p.Handle(method, handler)
+			}
+			if tt.settings {
+				p.HandleSettings(submit)
+			}
+			if tt.require {
+				p.HandleRequirements(submit)
+			}
+			err := p.checkBindings()
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected problems: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no problems, want %v", tt.problems)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("problems %q miss %q", err, problem)
+				}
+			}
+		})
+	}
+}
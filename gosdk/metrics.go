@@ -0,0 +1,368 @@
+package sdkv2
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+var (
+	// latencyBuckets are the bounds in seconds of the handler latency histogram
+	latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// jobBuckets are the bounds in seconds of the job duration histogram
+	jobBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200}
+)
+
+type metricKind string
+
+const (
+	counterMetric   metricKind = "counter"
+	gaugeMetric     metricKind = "gauge"
+	histogramMetric metricKind = "histogram"
+)
+
+// metric is a family of series, one per set of label values
+type metric struct {
+	name    string
+	help    string
+	kind    metricKind
+	labels  []string
+	buckets []float64 // histogram bounds
+
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string // label values
+	value  float64  // of a counter or gauge
+	counts []uint64 // of a histogram, per bucket
+	sum    float64
+	count  uint64
+}
+
+func newMetric(kind metricKind, name, help string, buckets []float64, labels ...string) *metric {
+	return &metric{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: map[string]*series{}}
+}
+
+// get returns the series of label values, the mutex must be held
+func (m *metric) get(values []string) *series {
+	key := strings.Join(values, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{values: values}
+		if m.kind == histogramMetric {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// add adds v to a counter or gauge
+func (m *metric) add(v float64, values ...string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.get(values).value += v
+}
+
+// observe records v in a histogram
+func (m *metric) observe(v float64, values ...string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s := m.get(values)
+	for i, bound := range m.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// snapshot copies the series sorted by label values
+func (m *metric) snapshot() []series {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	all := make([]series, 0, len(m.series))
+	for _, s := range m.series {
+		c := *s
+		c.counts = slices.Clone(s.counts)
+		all = append(all, c)
+	}
+	slices.SortFunc(all, func(a, b series) int {
+		return slices.Compare(a.values, b.values)
+	})
+	return all
+}
+
+// write writes the metric in the Prometheus text format
+func (m *metric) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+	for _, s := range m.snapshot() {
+		if m.kind != histogramMetric {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, m.labelSet(s.values, ""), formatValue(s.value))
+			continue
+		}
+		for i, bound := range m.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.labelSet(s.values, formatValue(bound)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.labelSet(s.values, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, m.labelSet(s.values, ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, m.labelSet(s.values, ""), s.count)
+	}
+}
+
+// labelSet formats label values, with the le label of a histogram bucket when given
+func (m *metric) labelSet(values []string, le string) string {
+	pairs := []string{}
+	for i, name := range m.labels {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelMap returns the labels of a series for a metric event
+func (m *metric) labelMap(values []string) map[string]string {
+	if len(m.labels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(m.labels))
+	for i, name := range m.labels {
+		labels[name] = values[i]
+	}
+	return labels
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// defaultJobExpiry is how long a job that never ends is tracked before it is forgotten
+const defaultJobExpiry = 24 * time.Hour
+
+// jobRun is a job in flight, for its duration
+type jobRun struct {
+	method string
+	start  time.Time
+}
+
+// sdkMetrics are the operational metrics the SDK keeps for its plugins
+type sdkMetrics struct {
+	actionsReceived      *metric
+	actionsAccepted      *metric
+	actionsRejected      *metric
+	handlerDuration      *metric
+	jobsActive           *metric
+	jobDuration          *metric
+	progressRetries      *metric
+	progressNoResponders *metric
+	eventFailures        *metric
+	jobsExpired          *metric
+	all                  []*metric
+
+	jobs      sync.Map      // jobId to its jobRun
+	jobExpiry time.Duration // jobs running longer are forgotten
+	lastSweep atomic.Int64  // unix nanoseconds of the last sweep for expired jobs
+}
+
+func newSDKMetrics() *sdkMetrics {
+	m := &sdkMetrics{
+		actionsReceived:      newMetric(counterMetric, "soren_actions_received_total", "Action requests received.", nil, "method"),
+		actionsAccepted:      newMetric(counterMetric, "soren_actions_accepted_total", "Action requests accepted as a job.", nil, "method"),
+		actionsRejected:      newMetric(counterMetric, "soren_actions_rejected_total", "Requests rejected with an error.", nil, "method", "code"),
+		handlerDuration:      newMetric(histogramMetric, "soren_handler_duration_seconds", "Time action handlers took to return.", latencyBuckets, "method"),
+		jobsActive:           newMetric(gaugeMetric, "soren_jobs_active", "Jobs accepted and not over yet.", nil),
+		jobDuration:          newMetric(histogramMetric, "soren_job_duration_seconds", "Time from accept to the end of a job.", jobBuckets, "method", "status"),
+		progressRetries:      newMetric(counterMetric, "soren_progress_retries_total", "Progress reports sent again after finding no responders.", nil),
+		progressNoResponders: newMetric(counterMetric, "soren_progress_no_responders_total", "Progress reports that found no responders.", nil),
+		eventFailures:        newMetric(counterMetric, "soren_event_send_failures_total", "Event requests that failed, spooled ones included.", nil),
+		jobsExpired:          newMetric(counterMetric, "soren_jobs_expired_total", "Jobs forgotten after running longer than the job expiry without ending.", nil),
+		jobExpiry:            defaultJobExpiry,
+	}
+	m.all = []*metric{
+		m.actionsReceived, m.actionsAccepted, m.actionsRejected, m.handlerDuration,
+		m.jobsActive, m.jobDuration, m.progressRetries, m.progressNoResponders, m.eventFailures, m.jobsExpired,
+	}
+	// series without labels are exported from the start
+	m.jobsActive.add(0)
+	m.progressRetries.add(0)
+	m.progressNoResponders.add(0)
+	m.eventFailures.add(0)
+	m.jobsExpired.add(0)
+	return m
+}
+
+// jobStarted counts an accepted job
+func (m *sdkMetrics) jobStarted(jobId, method string) {
+	m.actionsAccepted.add(1, method)
+	m.jobsActive.add(1)
+	now := time.Now()
+	m.jobs.Store(jobId, jobRun{method: method, start: now})
+	m.expireJobs(now)
+}
+
+// expireJobs forgets the jobs that never ended within the job expiry, at most once a minute
+func (m *sdkMetrics) expireJobs(now time.Time) {
+	last := m.lastSweep.Load()
+	if now.UnixNano()-last < int64(time.Minute) || !m.lastSweep.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	m.jobs.Range(func(jobId, run any) bool {
+		if now.Sub(run.(jobRun).start) > m.jobExpiry {
+			if _, ok := m.jobs.LoadAndDelete(jobId); ok {
+				m.jobsActive.add(-1)
+				m.jobsExpired.add(1)
+			}
+		}
+		return true
+	})
+}
+
+// jobEnded records the duration of a job, once
+func (m *sdkMetrics) jobEnded(jobId string, status models.JobStatus) {
+	run, ok := m.jobs.LoadAndDelete(jobId)
+	if !ok {
+		return
+	}
+	m.jobsActive.add(-1)
+	m.jobDuration.observe(time.Since(run.(jobRun).start).Seconds(), run.(jobRun).method, string(status))
+}
+
+// rejected counts a request failed with err
+func (m *sdkMetrics) rejected(method string, err error) {
+	m.actionsRejected.add(1, method, string(AsError(err).Code))
+}
+
+// WriteMetrics writes the SDK metrics in the Prometheus text format
+func (s *SorenSDK) WriteMetrics(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	for _, m := range s.metrics.all {
+		m.write(buf)
+	}
+	return buf.Flush()
+}
+
+// MetricsHandler serves the SDK metrics in the Prometheus text format
+func (s *SorenSDK) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.WriteMetrics(w)
+	})
+}
+
+// serveMetrics serves /metrics on addr until the SDK closes
+func (s *SorenSDK) serveMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+	s.metricsListener = listener
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.MetricsHandler())
+	s.metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := s.metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			baseLogger().Errorw("metrics server error", "error", err)
+		}
+	}()
+	return nil
+}
+
+// MetricsAddr returns the address the metrics listener is bound to, empty without one
+func (s *SorenSDK) MetricsAddr() string {
+	if s.metricsListener == nil {
+		return ""
+	}
+	return s.metricsListener.Addr().String()
+}
+
+// startMetricsReporter starts sending the metrics as events each interval, until the SDK closes
+func (s *SorenSDK) startMetricsReporter(interval time.Duration) {
+	logger := NewAsyncEventLogger(s, AsyncOptions{})
+	stop := make(chan struct{})
+	s.reportStop = stop
+	s.reporting.Add(1)
+	go func() {
+		defer s.reporting.Done()
+		defer logger.Close()
+		s.reportMetrics(logger, interval, stop)
+	}()
+}
+
+// stopMetricsReporter stops the metrics reporter and waits until its logger is flushed
+func (s *SorenSDK) stopMetricsReporter() {
+	s.mutex.Lock()
+	stop := s.reportStop
+	s.reportStop = nil
+	s.mutex.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	s.reporting.Wait()
+}
+
+// reportMetrics sends every series as metric events with logger each interval until stop is closed.
+// Histograms are sent as their count and sum.
+func (s *SorenSDK) reportMetrics(logger *EventLogger, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		for _, m := range s.metrics.all {
+			for _, series := range m.snapshot() {
+				labels := m.labelMap(series.values)
+				if m.kind != histogramMetric {
+					logger.Metric(models.MetricEvent{Name: m.name, Value: series.value, Labels: labels})
+					continue
+				}
+				logger.Metric(models.MetricEvent{Name: m.name + "_count", Value: float64(series.count), Labels: labels})
+				logger.Metric(models.MetricEvent{Name: m.name + "_sum", Value: series.sum, Unit: "s", Labels: labels})
+			}
+		}
+	}
+}
+
+// closeMetrics stops the metrics listener
+func (s *SorenSDK) closeMetrics() {
+	if s.metricsServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.metricsServer.Shutdown(ctx)
+}
@@ -0,0 +1,82 @@
+package sdkv2
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func TestExpireJobs(t *testing.T) {
+	m := newSDKMetrics()
+	m.jobExpiry = time.Hour
+	start := time.Now()
+	m.jobStarted("job-1", "scan")
+	m.jobs.Store("job-1", jobRun{method: "scan", start: start.Add(-2 * time.Hour)})
+	m.jobStarted("job-2", "scan")
+
+	// a sweep ran when job-2 started, the next one waits a minute
+	m.expireJobs(start.Add(30 * time.Second))
+	if _, ok := m.jobs.Load("job-1"); !ok {
+		t.Fatal("swept again within a minute")
+	}
+	m.expireJobs(start.Add(2 * time.Minute))
+	if _, ok := m.jobs.Load("job-1"); ok {
+		t.Error("the job over the expiry is still tracked")
+	}
+	if _, ok := m.jobs.Load("job-2"); !ok {
+		t.Error("the running job was forgotten")
+	}
+	if active := m.jobsActive.snapshot()[0].value; active != 1 {
+		t.Errorf("%v active jobs, want 1", active)
+	}
+	if expired := m.jobsExpired.snapshot()[0].value; expired != 1 {
+		t.Errorf("%v expired jobs, want 1", expired)
+	}
+	// an expired job that ends later is not counted twice
+	m.jobEnded("job-1", "done")
+	if active := m.jobsActive.snapshot()[0].value; active != 1 {
+		t.Errorf("%v active jobs after the expired one ended, want 1", active)
+	}
+}
+
+func TestCloseStopsMetricsReporter(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	defer ns.Shutdown()
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	var received atomic.Int64
+	nc.Subscribe("events.>", func(msg *nats.Msg) {
+		received.Add(1)
+		msg.Respond([]byte(`{"result":"OK"}`))
+	})
+	nc.Flush()
+
+	sdk, err := New(&Config{AgentURI: ns.ClientURL(), PluginID: "metrics-test", EventChannel: "events", MetricsInterval: 10 * time.Millisecond, DisableJobEvents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	sdk.Close()
+	sent := received.Load()
+	if sent == 0 {
+		t.Fatal("no metrics reported")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if received.Load() != sent {
+		t.Error("metrics reported after Close")
+	}
+	sdk.Close()
+}
@@ -0,0 +1,96 @@
+package sdkv2_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestMetrics(t *testing.T) {
+	const channel = "soren.plugin.event.test"
+	gw, err := gateway.Start(gateway.Options{PluginID: "bin.*.metrics", EventChannel: channel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	sdk, err := sdkv2.New(&sdkv2.Config{
+		AgentURI:         gw.URL(),
+		PluginID:         "bin.*.metrics",
+		EventChannel:     channel,
+		MetricsAddr:      "127.0.0.1:0",
+		MetricsInterval:  50 * time.Millisecond,
+		DisableJobEvents: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := sdkv2.NewPlugin(sdk)
+	plugin.SetIntro(models.PluginIntro{Name: "Metrics", Version: "1.0.0"}, nil)
+	plugin.AddActions([]models.Action{{Method: "run", Title: "Run"}, {Method: "refuse", Title: "Refuse"}})
+	plugin.Handle("run", func(req *sdkv2.Request) {
+		req.Accept()
+		req.Job().Done(nil)
+	})
+	plugin.Handle("refuse", func(req *sdkv2.Request) {
+		req.Fail(sdkv2.NewError(sdkv2.CodeForbidden, "not today"))
+	})
+	go plugin.Start()
+	if _, err := gw.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{"run", "run", "refuse"} {
+		inv, err := gw.Invoke(method, map[string]any{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inv.Updates == nil {
+			continue // rejected
+		}
+		for range inv.Updates {
+		}
+	}
+
+	resp, err := http.Get("http://" + sdk.MetricsAddr() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	text := string(body)
+	for _, line := range []string{
+		`soren_actions_received_total{method="run"} 2`,
+		`soren_actions_received_total{method="refuse"} 1`,
+		`soren_actions_accepted_total{method="run"} 2`,
+		`soren_actions_rejected_total{method="refuse",code="forbidden"} 1`,
+		`soren_handler_duration_seconds_count{method="run"} 2`,
+		`soren_handler_duration_seconds_bucket{method="run",le="+Inf"} 2`,
+		`soren_job_duration_seconds_count{method="run",status="done"} 2`,
+		`soren_jobs_active 0`,
+		`soren_progress_retries_total 0`,
+		"# TYPE soren_handler_duration_seconds histogram",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("metrics miss %q:\n%s", line, text)
+		}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	sdk.Close() // sends the buffered events
+	found := false
+	for _, event := range gw.Events() {
+		if event.Event == models.EventTypeMetric && event.Details["name"] == "soren_actions_accepted_total" {
+			labels, _ := event.Details["labels"].(map[string]any)
+			found = labels["method"] == "run" && event.Details["value"] == float64(2)
+		}
+	}
+	if !found {
+		t.Fatal("no soren_actions_accepted_total metric event")
+	}
+}
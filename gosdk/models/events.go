@@ -0,0 +1,37 @@
+package models
+
+// AuditEvent records who did what, the details of an EventTypeAudit event
+type AuditEvent struct {
+	Actor   string         `json:"actor"`             // user or service that acted
+	Action  string         `json:"action"`            // what was done, e.g. "settings.update"
+	Target  string         `json:"target,omitempty"`  // what it was done to
+	Outcome string         `json:"outcome,omitempty"` // e.g. "success" or "denied"
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// MetricEvent is a measurement, the details of an EventTypeMetric event
+type MetricEvent struct {
+	Name   string            `json:"name"`
+	Value  float64           `json:"value"`
+	Unit   string            `json:"unit,omitempty"` // e.g. "ms", "bytes"
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// AlertEvent asks for attention, the details of an EventTypeAlert event.
+// Alerts with the same DedupeKey are the same incident.
+type AlertEvent struct {
+	Severity  AlertSeverity  `json:"severity"`
+	Title     string         `json:"title"`
+	DedupeKey string         `json:"dedupeKey,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// JobEvent is a step of a job lifecycle, the details of an EventTypeJob event
+type JobEvent struct {
+	JobID    string    `json:"jobId"`
+	Status   JobStatus `json:"status"`
+	EntityID string    `json:"entityId,omitempty"`
+	Method   string    `json:"method,omitempty"`
+	Progress int       `json:"progress"`
+	Error    any       `json:"error,omitempty"` // the error of a failed job
+}
@@ -1,6 +1,10 @@
 package models
 
-import "github.com/nats-io/nats.go"
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
 
 // PluginIntro represents the plugin introduction response
 // Subject: soren.v2.<PLUGIN_ID>.@intro
@@ -22,12 +26,21 @@ type Requirements struct {
 // PluginAction represents a single plugin action
 // Subject: soren.v2.<PLUGIN_ID>.@actions
 type Action struct {
-	Method         string                  `json:"method"`
-	Description    string                  `json:"description"`
-	Title          string                  `json:"title"`
-	Icon           Icon                    `json:"icon"`
-	RequestHandler func(msg *nats.Msg)  `json:"-"`
-	Form           ActionFormBuilder       `json:"form"`
+	Method         string              `json:"method"`
+	Description    string              `json:"description"`
+	Title          string              `json:"title"`
+	Icon           Icon                `json:"icon"`
+	RequestHandler func(msg *nats.Msg) `json:"-"`
+	Form           ActionFormBuilder   `json:"form"`
+	Permissions    *Permissions        `json:"permissions,omitempty"`
+}
+
+// Permissions declares who may invoke an action, checked against the caller's token claims.
+// The caller needs one of Roles, every one of Scopes and one of EntityTypes; empty lists are not checked.
+type Permissions struct {
+	Roles       []string `json:"roles,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	EntityTypes []int    `json:"entityTypes,omitempty"`
 }
 
 // Icon represents an icon for an action
@@ -39,11 +52,11 @@ type Icon struct {
 // Settings represents the settings form configuration
 // Subject: soren.v2.<PLUGIN_ID>.@settings
 type Settings struct {
-	ReplyTo    string         `json:"replyTo"`
-	Jsonui     map[string]any `json:"jsonui"`
-	Jsonschema map[string]any `json:"jsonschema"`
-	Data       map[string]any `json:"data"` // Current settings data
-	Handler func(msg *nats.Msg) any `json:"-"`
+	ReplyTo    string                  `json:"replyTo"`
+	Jsonui     map[string]any          `json:"jsonui"`
+	Jsonschema map[string]any          `json:"jsonschema"`
+	Data       map[string]any          `json:"data"` // Current settings data
+	Handler    func(msg *nats.Msg) any `json:"-"`
 }
 
 // ActionFormBuilder represents the action form configuration
@@ -59,8 +72,15 @@ type PluginEvent struct {
 	Level     LogLevel       `json:"level" bson:"level"`
 	Source    string         `json:"source" bson:"source"`
 	Message   string         `json:"message" bson:"message"`
-	Timestamp uint64         `json:"timestamp" bson:"timestamp"`
+	Timestamp uint64         `json:"timestamp" bson:"timestamp"` // Unix seconds
 	Details   map[string]any `json:"details" bson:"details"`
+	// Time is when the event happened with sub second precision, sent as RFC3339Nano
+	Time time.Time `json:"time,omitzero" bson:"time,omitempty"`
+	// Links to the job and request that produced the event
+	JobID         string `json:"jobId,omitempty" bson:"jobId,omitempty"`
+	EntityID      string `json:"entityId,omitempty" bson:"entityId,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty" bson:"correlationId,omitempty"`
+	TraceID       string `json:"traceId,omitempty" bson:"traceId,omitempty"`
 }
 
 type JobProgress struct {
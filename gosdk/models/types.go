@@ -4,8 +4,6 @@ type Command string
 type EventType string
 type LogLevel string
 
-
-
 const (
 	ProgressCommand       Command = "progress"
 	StopCommand           Command = "stop"
@@ -13,11 +11,33 @@ const (
 	ContextPathCommand    Command = "context/path"
 )
 
+const (
+	EventTypeLog    EventType = "log"
+	EventTypeAudit  EventType = "audit"
+	EventTypeMetric EventType = "metric"
+	EventTypeAlert  EventType = "alert"
+	EventTypeJob    EventType = "job"
+)
+
+// JobStatus is the step of a job lifecycle event
+type JobStatus string
 
 const (
-	EventTypeLog EventType = "log"
+	JobStatusAccepted  JobStatus = "accepted"
+	JobStatusProgress  JobStatus = "progress"
+	JobStatusDone      JobStatus = "done"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
 )
 
+// AlertSeverity is how urgent an alert is
+type AlertSeverity string
+
+const (
+	AlertSeverityInfo     AlertSeverity = "info"
+	AlertSeverityWarning  AlertSeverity = "warning"
+	AlertSeverityCritical AlertSeverity = "critical"
+)
 
 const (
 	LogLevelDebug LogLevel = "debug"
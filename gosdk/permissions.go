@@ -0,0 +1,69 @@
+package sdkv2
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+// checkPermissions reports why claims do not satisfy perms, nil when they do
+func checkPermissions(perms *models.Permissions, claims *Claims) error {
+	if perms == nil {
+		return nil
+	}
+	if claims == nil {
+		return ErrMissingToken
+	}
+	if len(perms.Roles) > 0 && !slices.ContainsFunc(perms.Roles, func(role string) bool {
+		return slices.Contains(claims.Roles, role)
+	}) {
+		return fmt.Errorf("one of roles %v is required", perms.Roles)
+	}
+	for _, scope := range perms.Scopes {
+		if !slices.Contains(claims.Scopes, scope) {
+			return fmt.Errorf("scope %s is required", scope)
+		}
+	}
+	if len(perms.EntityTypes) > 0 && !slices.Contains(perms.EntityTypes, claims.Type) {
+		return fmt.Errorf("entity type %d is not allowed", claims.Type)
+	}
+	return nil
+}
+
+// permit checks the caller against the permissions declared on an action.
+// Requests that are not permitted are rejected and false is returned.
+func (p *Plugin) permit(req *Request, action models.Action) bool {
+	if action.Permissions == nil {
+		return true
+	}
+	claims := req.Claims()
+	if claims == nil {
+		req.Fail(WrapError(CodeUnauthorized, ErrMissingToken))
+		return false
+	}
+	// claims decoded without a signature check are whatever the caller wrote
+	if !claims.Verified {
+		req.Fail(WrapError(CodeUnauthorized, ErrUnverifiedToken))
+		return false
+	}
+	if err := checkPermissions(action.Permissions, claims); err != nil {
+		req.Fail(WrapError(CodeForbidden, err).WithDetails(map[string]any{"permissions": action.Permissions}))
+		return false
+	}
+	return true
+}
+
+// checkPermissionKeys fails when actions declare permissions but no auth public keys
+// are configured, their callers could not be verified
+func (p *Plugin) checkPermissionKeys() error {
+	if len(p.sdk.authKeys) > 0 {
+		return nil
+	}
+	for _, action := range p.Actions {
+		if action.Permissions != nil {
+			return fmt.Errorf("action %s declares permissions, configure auth public keys to enforce them", action.Method)
+		}
+	}
+	return nil
+}
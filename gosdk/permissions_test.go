@@ -0,0 +1,93 @@
+package sdkv2
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	perms := &models.Permissions{Roles: []string{"admin", "owner"}, Scopes: []string{"repo:write"}, EntityTypes: []int{10}}
+	cases := []struct {
+		name   string
+		perms  *models.Permissions
+		claims *Claims
+		ok     bool
+	}{
+		{"no permissions", nil, nil, true},
+		{"allowed", perms, &Claims{Roles: []string{"owner"}, Scopes: []string{"repo:read", "repo:write"}, Type: 10}, true},
+		{"missing token", perms, nil, false},
+		{"missing role", perms, &Claims{Roles: []string{"viewer"}, Scopes: []string{"repo:write"}, Type: 10}, false},
+		{"missing scope", perms, &Claims{Roles: []string{"admin"}, Scopes: []string{"repo:read"}, Type: 10}, false},
+		{"wrong entity type", perms, &Claims{Roles: []string{"admin"}, Scopes: []string{"repo:write"}, Type: 20}, false},
+	}
+	for _, c := range cases {
+		if err := checkPermissions(c.perms, c.claims); (err == nil) != c.ok {
+			t.Errorf("%s: got error %v", c.name, err)
+		}
+	}
+	if err := checkPermissions(perms, nil); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("missing token: got %v, want ErrMissingToken", err)
+	}
+}
+
+func TestPermit(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := models.Action{Method: "delete", Permissions: &models.Permissions{Roles: []string{"admin"}}}
+	cases := []struct {
+		name   string
+		header string  // Authorization header
+		claims *Claims // verified by authorize before permit runs
+		ok     bool
+		code   ErrorCode
+	}{
+		{"allowed", "", &Claims{Roles: []string{"admin"}, Verified: true}, true, ""},
+		{"forbidden", "", &Claims{Roles: []string{"viewer"}, Verified: true}, false, CodeForbidden},
+		{"missing token", "", nil, false, CodeUnauthorized},
+		{"unverified token", "Bearer " + signToken(t, privateKey, `{"pluginId":"p","roles":["admin"]}`), nil, false, CodeUnauthorized},
+	}
+	for _, c := range cases {
+		p := &Plugin{sdk: &SorenSDK{pluginID: "p", metrics: newSDKMetrics()}}
+		msg := &nats.Msg{Subject: "soren.cpu.p.delete", Header: nats.Header{}}
+		if c.header != "" {
+			msg.Header.Set("Authorization", c.header)
+		}
+		req := &Request{Msg: msg, Method: action.Method, Headers: msg.Header, plugin: p, claims: c.claims}
+		if ok := p.permit(req, action); ok != c.ok {
+			t.Errorf("%s: permit = %v, want %v", c.name, ok, c.ok)
+			continue
+		}
+		rejected := p.sdk.metrics.actionsRejected.snapshot()
+		if c.ok {
+			if len(rejected) != 0 {
+				t.Errorf("%s: permitted request was rejected", c.name)
+			}
+			continue
+		}
+		if len(rejected) != 1 || rejected[0].values[1] != string(c.code) {
+			t.Errorf("%s: rejections %+v, want code %s", c.name, rejected, c.code)
+		}
+	}
+}
+
+func TestCheckPermissionKeys(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+	p := &Plugin{sdk: &SorenSDK{}, Actions: []models.Action{{Method: "list"}}}
+	if err := p.checkPermissionKeys(); err != nil {
+		t.Fatalf("action without permissions: %v", err)
+	}
+	p.Actions = append(p.Actions, models.Action{Method: "delete", Permissions: &models.Permissions{Roles: []string{"admin"}}})
+	if err := p.checkPermissionKeys(); err == nil {
+		t.Fatal("permissions without auth keys accepted")
+	}
+	p.sdk.authKeys = []ed25519.PublicKey{publicKey}
+	if err := p.checkPermissionKeys(); err != nil {
+		t.Fatalf("permissions with auth keys: %v", err)
+	}
+}
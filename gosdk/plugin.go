@@ -1,41 +1,61 @@
 package sdkv2
 
 import (
-	"log"
 	"strings"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/nats-io/nats.go"
-	"github.com/sorenhq/go-plugin-sdk/logtool"
 )
 
+// introReply is the reply to @intro
+func (p *Plugin) introReply() ([]byte, error) {
+	return sonic.Marshal(p.Intro)
+}
+
+// settingsReply is the reply to @settings, empty when the plugin has no settings
+func (p *Plugin) settingsReply() ([]byte, error) {
+	if p.Settings == nil {
+		return nil, nil
+	}
+	return sonic.Marshal(p.Settings)
+}
+
+// actionsReply is the reply to @actions
+func (p *Plugin) actionsReply() ([]byte, error) {
+	return sonic.Marshal(p.Actions)
+}
+
 func (p *Plugin) IntroHandler() error {
-	p.sdk.conn.Subscribe(p.sdk.makeIntroSubject(), func(msg *nats.Msg) {
+	p.sdk.subscribe(p.sdk.makeIntroSubject(), func(msg *nats.Msg) {
 		// Handle the intro message
-		introByte, err := sonic.Marshal(p.Intro)
+		introByte, err := p.introReply()
 		if err != nil {
 			return
 		}
-		msg.Respond(introByte)
+		respond(msg, introByte)
 	})
 	if p.Intro.Requirements != nil {
 		if strings.TrimSpace(p.Intro.Requirements.ReplyTo) == "" {
-			log.Println("no setting service defined")
+			p.Logger().Warn("no requirements service defined")
 			return nil
 		}
-		p.sdk.conn.Subscribe(p.sdk.makeSubject(p.Intro.Requirements.ReplyTo), func(msg *nats.Msg) {
-			if p.Intro.Requirements.Handler == nil {
-				msg.Respond([]byte(`{"status":"not implemented"}`))
+		p.sdk.subscribe(p.sdk.makeSubject(p.Intro.Requirements.ReplyTo), func(msg *nats.Msg) {
+			handler := p.requirementsHandler
+			if handler == nil && p.Intro.Requirements.Handler != nil {
+				handler = MsgSubmitHandler(p.Intro.Requirements.Handler)
+			}
+			if handler == nil {
+				respond(msg, []byte(`{"status":"not implemented"}`))
+				return
+			}
+			req := newRequest(p, msg, p.Intro.Requirements.ReplyTo)
+			_, req.span = p.sdk.startSpan(msg, "requirements submit")
+			defer req.span.End()
+			if !p.authorize(req) {
 				return
 			}
-			p.Intro.Requirements.Handler(msg)
-			// result:=
-			// resByte,err:=sonic.Marshal(result)
-			// if err!=nil{
-			// 	log.Println("submit required info on init plugin error:",err)
-			// 	return
-			// }
-			// msg.Respond(resByte)
+			p.submit(req, handler)
 		})
 	}
 	return nil
@@ -43,18 +63,20 @@ func (p *Plugin) IntroHandler() error {
 
 func (p *Plugin) SettingsHandler() error {
 	// show settings form handler
-	p.sdk.conn.Subscribe(p.sdk.makeSettingsSubject(), func(msg *nats.Msg) {
-		logtool.GetLogger().Info("Settings Called")
-		// Handle the settings message
-		if p.Settings == nil {
-			msg.Respond(nil)
+	p.sdk.subscribe(p.sdk.makeSettingsSubject(), func(msg *nats.Msg) {
+		req := newRequest(p, msg, "@settings")
+		req.Logger().Info("settings called")
+		_, req.span = p.sdk.startSpan(msg, "settings")
+		defer req.span.End()
+		if !p.authorize(req) {
 			return
 		}
-		settingsByte, err := sonic.Marshal(p.Settings)
+		// Handle the settings message
+		settingsByte, err := p.settingsReply()
 		if err != nil {
 			return
 		}
-		msg.Respond(settingsByte)
+		respond(msg, settingsByte)
 	})
 	// settings submit handler
 	if p.Settings != nil {
@@ -63,65 +85,117 @@ func (p *Plugin) SettingsHandler() error {
 			// log.Println("no setting service defined")
 			// return nil
 		}
-		p.sdk.conn.Subscribe(p.sdk.makeSubject(p.Settings.ReplyTo), func(msg *nats.Msg) {
-			if p.Settings.Handler == nil {
-				msg.Respond([]byte(`{"status":"not implemented"}`))
+		p.sdk.subscribe(p.sdk.makeSubject(p.Settings.ReplyTo), func(msg *nats.Msg) {
+			handler := p.settingsHandler
+			if handler == nil && p.Settings.Handler != nil {
+				handler = MsgSubmitHandler(p.Settings.Handler)
+			}
+			if handler == nil {
+				respond(msg, []byte(`{"status":"not implemented"}`))
+				return
+			}
+			req := newRequest(p, msg, p.Settings.ReplyTo)
+			_, req.span = p.sdk.startSpan(msg, "settings submit")
+			defer req.span.End()
+			if !p.authorize(req) {
 				return
 			}
-			p.Settings.Handler(msg)
-			// resByte,err:=sonic.Marshal(result)
-			// if err!=nil{
-			// 	log.Println("settings handler response error:",err)
-			// 	return
-			// }
-			// msg.Respond(resByte)
+			p.submit(req, handler)
 		})
 	}
 
 	return nil
 }
 
+// authorize verifies the Authorization token of a request when auth public keys are configured.
+// Requests that fail verification are rejected and false is returned.
+func (p *Plugin) authorize(req *Request) bool {
+	if len(p.sdk.authKeys) == 0 {
+		return true
+	}
+	claims, err := verifyToken(authToken(req.Header("Authorization")), p.sdk.authKeys, p.sdk.pluginID, p.sdk.claimNames, time.Now())
+	if err != nil {
+		req.Logger().Warnw("unauthorized request", "subject", req.Subject(), "error", err)
+		req.Fail(WrapError(CodeUnauthorized, err))
+		return false
+	}
+	req.claims = claims
+	return true
+}
+
+// submit runs a submission handler and replies with its result
+func (p *Plugin) submit(req *Request, handler SubmitHandler) {
+	result := handler(req)
+	if result == nil || req.Replied() {
+		return
+	}
+	if err := req.Reply(result); err != nil {
+		req.Logger().Errorw("submission reply error", "error", err)
+	}
+}
+
 func (p *Plugin) ActionsHandler() {
-	p.sdk.conn.Subscribe(p.sdk.makeActionsListSubject(), func(msg *nats.Msg) {
+	p.sdk.subscribe(p.sdk.makeActionsListSubject(), func(msg *nats.Msg) {
 		// Handle the actions list message
-		listBytes, err := sonic.Marshal(p.Actions)
+		listBytes, err := p.actionsReply()
 		if err != nil {
-			log.Printf("Failed to marshal actions: %v", err)
+			p.Logger().Errorw("failed to marshal actions", "error", err)
 			return
 		}
-		msg.Respond(listBytes)
+		respond(msg, listBytes)
 	})
-	for _,action:=range p.Actions{
-		_,err:=p.sdk.conn.Subscribe(p.sdk.makeFormSubject(action.Method),func(msg *nats.Msg) {
+	for _, action := range p.Actions {
+		_, err := p.sdk.subscribe(p.sdk.makeFormSubject(action.Method), func(msg *nats.Msg) {
 			// Handle the action message
-			formBody,err:=sonic.Marshal(action.Form)
-			if err!=nil{
-				log.Println("action form ",action.Title," error:",err)
-				return 
+			req := newRequest(p, msg, action.Method)
+			_, req.span = p.sdk.startSpan(msg, "form "+action.Method)
+			defer req.span.End()
+			if !p.authorize(req) {
+				return
 			}
-			msg.Respond(formBody)
+			formBody, err := sonic.Marshal(action.Form)
+			if err != nil {
+				req.Logger().Errorw("action form error", "title", action.Title, "error", err)
+				return
+			}
+			respond(msg, formBody)
 		})
-		if err!=nil{
-			log.Printf("subscribe error: %s on %s\n",err.Error(),p.sdk.makeFormSubject(action.Method))
-			return 
+		if err != nil {
+			p.Logger().Errorw("subscribe error", "subject", p.sdk.makeFormSubject(action.Method), "error", err)
+			return
 		}
-		log.Printf("Form Builder Service : %s",p.sdk.makeFormSubject(action.Method))
+		p.Logger().Infow("form builder service", "subject", p.sdk.makeFormSubject(action.Method))
 		// request handler make a jobId and respond it with the result
-		_,err=p.sdk.conn.Subscribe(p.sdk.makeActionCpu(action.Method),func(msg *nats.Msg) {
-			action.RequestHandler(msg)
-			// result:=
-			// resByte,err:=sonic.Marshal(result)
-			// if err!=nil{
-			// 	log.Println("action response ",action.Title," error:",err)
-			// 	return 
-			// }
-			// msg.Respond(resByte)
+		_, err = p.sdk.subscribe(p.sdk.makeActionCpu(action.Method), func(msg *nats.Msg) {
+			p.sdk.metrics.actionsReceived.add(1, action.Method)
+			req := newRequest(p, msg, action.Method)
+			_, req.span = p.sdk.startSpan(msg, "action "+action.Method)
+			defer req.span.End()
+			if !p.authorize(req) || !p.permit(req, action) {
+				return
+			}
+			// a retried request is answered with the job it already started
+			if jobId, ok := p.accepted.Get(idempotencyKey(msg)); ok {
+				respondJobId(msg, jobId)
+				return
+			}
+			if !p.limit(req) {
+				return
+			}
+			handler := p.actionHandler(action)
+			if handler == nil {
+				req.Fail(NewError(CodeUnavailable, "not implemented"))
+				return
+			}
+			start := time.Now()
+			handler(req)
+			p.sdk.metrics.handlerDuration.observe(time.Since(start).Seconds(), action.Method)
 		})
-		if err!=nil{
-			log.Printf("subscribe error: %s on %s\n",err.Error(),p.sdk.makeActionCpu(action.Method))
-			return 
+		if err != nil {
+			p.Logger().Errorw("subscribe error", "subject", p.sdk.makeActionCpu(action.Method), "error", err)
+			return
 		}
-		log.Printf("Subscribed Action : %s",p.sdk.makeActionCpu(action.Method))
+		p.Logger().Infow("subscribed action", "subject", p.sdk.makeActionCpu(action.Method))
 	}
 
 }
@@ -0,0 +1,246 @@
+package sdkv2
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/nats-io/nats.go"
+)
+
+// RateLimit is a token bucket of Burst tokens refilled at Rate tokens per Per
+type RateLimit struct {
+	Rate  int
+	Per   time.Duration
+	Burst int // defaults to Rate
+}
+
+func (l RateLimit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Rate)
+}
+
+// refill returns the tokens added per second
+func (l RateLimit) refill() float64 {
+	if l.Per <= 0 {
+		return float64(l.Rate)
+	}
+	return float64(l.Rate) / l.Per.Seconds()
+}
+
+// RateLimits configures the limits checked before an action handler runs
+type RateLimits struct {
+	Action       map[string]RateLimit // per action method, shared by every entity
+	Entity       *RateLimit           // per requester entity, shared by every action
+	ActionEntity map[string]RateLimit // per action method and requester entity
+	// Store keeps the buckets, in memory when nil. Use NewKVRateLimitStore to share them across replicas.
+	Store RateLimitStore
+}
+
+// RateLimitStore keeps token buckets
+type RateLimitStore interface {
+	// Take removes a token from the bucket of key. When the bucket is empty it returns
+	// false and how long until a token is available.
+	Take(key string, limit RateLimit, now time.Time) (bool, time.Duration, error)
+	// Return puts back a token taken from the bucket of key, for a request another limit denied
+	Return(key string, limit RateLimit) error
+}
+
+// bucket is the state of a token bucket
+type bucket struct {
+	Tokens  float64 `json:"tokens"`
+	Updated int64   `json:"updated"` // unix nano
+}
+
+// take refills the bucket up to now and removes a token if there is one
+func (b *bucket) take(limit RateLimit, now time.Time) (bool, time.Duration) {
+	capacity := limit.capacity()
+	if b.Updated == 0 {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(time.Unix(0, b.Updated)); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed.Seconds()*limit.refill())
+	}
+	b.Updated = now.UnixNano()
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	if limit.refill() <= 0 {
+		return false, limit.Per
+	}
+	return false, time.Duration((1 - b.Tokens) / limit.refill() * float64(time.Second))
+}
+
+// give puts a token back, up to the capacity
+func (b *bucket) give(limit RateLimit) {
+	b.Tokens = math.Min(limit.capacity(), b.Tokens+1)
+}
+
+type memoryRateLimitStore struct {
+	buckets map[string]*bucket
+	mutex   sync.Mutex
+}
+
+// NewMemoryRateLimitStore keeps token buckets in process memory
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *memoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{}
+		s.buckets[key] = b
+	}
+	allowed, retryAfter := b.take(limit, now)
+	return allowed, retryAfter, nil
+}
+
+func (s *memoryRateLimitStore) Return(key string, limit RateLimit) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if b, ok := s.buckets[key]; ok {
+		b.give(limit)
+	}
+	return nil
+}
+
+type kvRateLimitStore struct {
+	kv nats.KeyValue
+}
+
+// NewKVRateLimitStore keeps token buckets in a JetStream key value bucket shared by every replica
+func NewKVRateLimitStore(kv nats.KeyValue) RateLimitStore {
+	return &kvRateLimitStore{kv: kv}
+}
+
+func (s *kvRateLimitStore) Take(key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	var allowed bool
+	var retryAfter time.Duration
+	err := s.update(key, func(b *bucket) {
+		allowed, retryAfter = b.take(limit, now)
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed, retryAfter, nil
+}
+
+func (s *kvRateLimitStore) Return(key string, limit RateLimit) error {
+	return s.update(key, func(b *bucket) {
+		if b.Updated != 0 {
+			b.give(limit)
+		}
+	})
+}
+
+// update applies change to the bucket of key with compare and swap,
+// retrying when another replica won the race
+func (s *kvRateLimitStore) update(key string, change func(b *bucket)) error {
+	kvKey := base64.RawURLEncoding.EncodeToString([]byte(key))
+	for range 5 {
+		b := bucket{}
+		var revision uint64
+		entry, err := s.kv.Get(kvKey)
+		switch {
+		case err == nil:
+			revision = entry.Revision()
+			if err := sonic.Unmarshal(entry.Value(), &b); err != nil {
+				return fmt.Errorf("invalid rate limit bucket %s: %w", key, err)
+			}
+		case !errors.Is(err, nats.ErrKeyNotFound):
+			return err
+		}
+		change(&b)
+		value, err := sonic.Marshal(b)
+		if err != nil {
+			return err
+		}
+		if revision == 0 {
+			_, err = s.kv.Create(kvKey, value)
+		} else {
+			_, err = s.kv.Update(kvKey, value, revision)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, nats.ErrKeyExists) {
+			return err
+		}
+	}
+	return fmt.Errorf("rate limit bucket %s is contended", key)
+}
+
+// SetRateLimits configures the rate limits of the plugin actions
+func (p *Plugin) SetRateLimits(limits RateLimits) {
+	if limits.Store == nil {
+		limits.Store = NewMemoryRateLimitStore()
+	}
+	p.rateLimits = &limits
+}
+
+// limit takes a token from every bucket the request falls in.
+// Requests over a limit are rejected with the rate_limited code and false is returned,
+// the tokens taken from the other buckets are given back.
+func (p *Plugin) limit(req *Request) bool {
+	if p.rateLimits == nil {
+		return true
+	}
+	entityId := req.EntityID
+	if entityId == "" {
+		if claims := req.Claims(); claims != nil {
+			entityId = claims.SpaceID
+		}
+	}
+	type check struct {
+		scope string
+		key   string
+		limit RateLimit
+	}
+	checks := []check{}
+	if limit, ok := p.rateLimits.Action[req.Method]; ok {
+		checks = append(checks, check{"action", "action:" + req.Method, limit})
+	}
+	if entityId != "" {
+		if p.rateLimits.Entity != nil {
+			checks = append(checks, check{"entity", "entity:" + entityId, *p.rateLimits.Entity})
+		}
+		if limit, ok := p.rateLimits.ActionEntity[req.Method]; ok {
+			checks = append(checks, check{"action_entity", "action_entity:" + req.Method + ":" + entityId, limit})
+		}
+	}
+	now := time.Now()
+	taken := []check{}
+	for _, c := range checks {
+		allowed, retryAfter, err := p.rateLimits.Store.Take(p.ID()+":"+c.key, c.limit, now)
+		if err != nil {
+			// an unavailable store must not take the plugin down
+			req.Logger().Errorw("rate limit error", "scope", c.scope, "error", err)
+			continue
+		}
+		if allowed {
+			taken = append(taken, c)
+			continue
+		}
+		// the request does not run, it must not use up the other limits
+		for _, t := range taken {
+			if err := p.rateLimits.Store.Return(p.ID()+":"+t.key, t.limit); err != nil {
+				req.Logger().Errorw("rate limit error", "scope", t.scope, "error", err)
+			}
+		}
+		req.Fail(NewError(CodeRateLimited, "rate limit exceeded").WithDetails(map[string]any{
+			"scope":      c.scope,
+			"retryAfter": int(math.Ceil(retryAfter.Seconds())),
+		}))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package sdkv2
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func TestBucket(t *testing.T) {
+	limit := RateLimit{Rate: 2, Per: time.Second}
+	now := time.Unix(1000, 0)
+	b := bucket{}
+	for i := range 2 {
+		if ok, _ := b.take(limit, now); !ok {
+			t.Fatalf("take %d denied", i)
+		}
+	}
+	ok, retryAfter := b.take(limit, now)
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Fatalf("empty bucket: allowed %v, retry after %s", ok, retryAfter)
+	}
+	if ok, _ := b.take(limit, now.Add(500*time.Millisecond)); !ok {
+		t.Fatal("refilled token denied")
+	}
+	b.give(limit)
+	b.give(limit)
+	b.give(limit)
+	if b.Tokens != limit.capacity() {
+		t.Fatalf("gave back past the capacity: %v tokens", b.Tokens)
+	}
+}
+
+func TestLimitReturnsTokens(t *testing.T) {
+	p := &Plugin{sdk: &SorenSDK{pluginID: "p", metrics: newSDKMetrics()}}
+	p.SetRateLimits(RateLimits{
+		Action:       map[string]RateLimit{"scan": {Rate: 2, Per: time.Hour}},
+		ActionEntity: map[string]RateLimit{"scan": {Rate: 1, Per: time.Hour}},
+	})
+	request := func(entityId string) *Request {
+		return &Request{Msg: &nats.Msg{Subject: "soren.cpu.p.scan"}, Method: "scan", EntityID: entityId, plugin: p}
+	}
+	steps := []struct {
+		entityId string
+		ok       bool
+	}{
+		{"a", true},
+		{"a", false}, // over the entity limit, the action token is given back
+		{"a", false},
+		{"b", true},
+		{"c", false}, // over the action limit
+	}
+	for i, s := range steps {
+		if ok := p.limit(request(s.entityId)); ok != s.ok {
+			t.Fatalf("request %d from %s: allowed %v, want %v", i, s.entityId, ok, s.ok)
+		}
+	}
+	rejected := p.sdk.metrics.actionsRejected.snapshot()
+	if len(rejected) != 1 || rejected[0].values[1] != string(CodeRateLimited) {
+		t.Errorf("rejections %+v", rejected)
+	}
+}
+
+func TestKVRateLimitStore(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	defer ns.Shutdown()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	conn, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "ratelimits"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replicas share the buckets, concurrent takes go through compare and swap
+	stores := []RateLimitStore{NewKVRateLimitStore(kv), NewKVRateLimitStore(kv)}
+	limit := RateLimit{Rate: 5, Per: time.Hour}
+	now := time.Now()
+
+	var mutex sync.Mutex
+	allowed, failed := 0, 0
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _, err := stores[i%2].Take("p:action:scan", limit, now)
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch {
+			case err != nil:
+				failed++
+			case ok:
+				allowed++
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed > 5 || allowed == 0 {
+		t.Fatalf("%d takes allowed and %d contended, want at most 5 tokens", allowed, failed)
+	}
+
+	for range 5 - allowed {
+		if ok, _, err := stores[0].Take("p:action:scan", limit, now); err != nil || !ok {
+			t.Fatalf("take: %v %v", ok, err)
+		}
+	}
+	if ok, retryAfter, err := stores[1].Take("p:action:scan", limit, now); err != nil || ok || retryAfter <= 0 {
+		t.Fatalf("empty bucket: allowed %v, retry after %s, error %v", ok, retryAfter, err)
+	}
+	if err := stores[1].Return("p:action:scan", limit); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := stores[0].Take("p:action:scan", limit, now); err != nil || !ok {
+		t.Fatalf("returned token: allowed %v, error %v", ok, err)
+	}
+}
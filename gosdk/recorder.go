@@ -0,0 +1,200 @@
+package sdkv2
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// RecordDirection tells which way a recorded message went
+type RecordDirection string
+
+const (
+	RecordIn       RecordDirection = "in"       // a request the plugin received
+	RecordReply    RecordDirection = "reply"    // the plugin reply to an in record
+	RecordOut      RecordDirection = "out"      // a request the plugin sent, progress or events
+	RecordResponse RecordDirection = "response" // the response to an out record
+)
+
+// redactedHeaders carry credentials, their values are not written to the record file
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// redacted marks the value of a header that was not recorded
+const redacted = "[REDACTED]"
+
+// Record is a message the SDK handled, as written to the record file.
+// A reply or response has the ID of the request it answers.
+type Record struct {
+	ID        uint64          `json:"id"`
+	Direction RecordDirection `json:"direction"`
+	Time      time.Time       `json:"time"`
+	Subject   string          `json:"subject"`
+	Header    nats.Header     `json:"header,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"` // the payload when it is JSON
+	Raw       []byte          `json:"raw,omitempty"`  // the payload otherwise
+	Error     string          `json:"error,omitempty"`
+}
+
+// Payload returns the message payload
+func (r Record) Payload() []byte {
+	if r.Data != nil {
+		return r.Data
+	}
+	return r.Raw
+}
+
+// ReadRecords reads a record file
+func ReadRecords(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records := []Record{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		record := Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// recorder writes the messages the SDK handles to a JSONL file
+type recorder struct {
+	file  *os.File
+	mutex sync.Mutex
+	seq   atomic.Uint64
+}
+
+// pendingReply is a recorded request waiting for its reply
+type pendingReply struct {
+	recorder *recorder
+	id       uint64
+}
+
+// pendingReplies maps the recorded requests that expect a reply to their record, while their handler runs
+var pendingReplies sync.Map
+
+func newRecorder(path string) (*recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record file: %w", err)
+	}
+	return &recorder{file: file}, nil
+}
+
+func (r *recorder) next() uint64 {
+	return r.seq.Add(1)
+}
+
+func (r *recorder) write(id uint64, direction RecordDirection, msg *nats.Msg, err error) {
+	record := Record{ID: id, Direction: direction, Time: time.Now()}
+	if msg != nil {
+		record.Subject = msg.Subject
+		record.Header = redactHeader(msg.Header)
+		if json.Valid(msg.Data) {
+			record.Data = msg.Data
+		} else {
+			record.Raw = msg.Data
+		}
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	line, merr := json.Marshal(record)
+	if merr != nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.file.Write(append(line, '\n'))
+}
+
+func (r *recorder) close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.file.Close()
+}
+
+// startRecording opens the record file
+func (s *SorenSDK) startRecording(path string) error {
+	rec, err := newRecorder(path)
+	if err != nil {
+		return err
+	}
+	s.recorder = rec
+	return nil
+}
+
+// subscribe subscribes handler to subject, recording the messages when recording is on.
+// Replies are recorded by respond while the handler runs, the message reply subject is left untouched.
+func (s *SorenSDK) subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	if s.recorder == nil {
+		return s.conn.Subscribe(subject, handler)
+	}
+	return s.conn.Subscribe(subject, func(msg *nats.Msg) {
+		id := s.recorder.next()
+		s.recorder.write(id, RecordIn, msg, nil)
+		if msg.Reply != "" {
+			pendingReplies.Store(msg, pendingReply{recorder: s.recorder, id: id})
+			defer pendingReplies.Delete(msg)
+		}
+		handler(msg)
+	})
+}
+
+// respond replies to msg, recording the reply when the request was recorded
+func respond(msg *nats.Msg, data []byte) error {
+	pending, ok := pendingReplies.LoadAndDelete(msg)
+	if !ok {
+		return msg.Respond(data)
+	}
+	reply := pending.(pendingReply)
+	err := msg.Respond(data)
+	reply.recorder.write(reply.id, RecordReply, &nats.Msg{Subject: msg.Reply, Data: data}, err)
+	return err
+}
+
+// requestMsg sends a request, recording it and its response when recording is on
+func (s *SorenSDK) requestMsg(msg *nats.Msg, timeout time.Duration) (*nats.Msg, error) {
+	if s.recorder == nil {
+		return s.conn.RequestMsg(msg, timeout)
+	}
+	id := s.recorder.next()
+	s.recorder.write(id, RecordOut, msg, nil)
+	resp, err := s.conn.RequestMsg(msg, timeout)
+	s.recorder.write(id, RecordResponse, resp, err)
+	return resp, err
+}
+
+// redactHeader returns a copy of header with the credential values replaced
+func redactHeader(header nats.Header) nats.Header {
+	if header == nil {
+		return nil
+	}
+	out := make(nats.Header, len(header))
+	for key, values := range header {
+		for _, name := range redactedHeaders {
+			if strings.EqualFold(key, name) {
+				values = []string{redacted}
+				break
+			}
+		}
+		out[key] = values
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package sdkv2
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func TestRecorderRedactsCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	r, err := newRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &nats.Msg{Subject: "soren.cpu.p.delete", Header: nats.Header{}, Data: []byte(`{}`)}
+	msg.Header.Set("Authorization", "Bearer secret")
+	msg.Header.Set("traceparent", "00-trace-span-01")
+	r.write(r.next(), RecordIn, msg, nil)
+	r.close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("record file mode %o, want 600", mode)
+	}
+	records, err := ReadRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	if got := records[0].Header.Get("Authorization"); got != redacted {
+		t.Errorf("Authorization recorded as %q", got)
+	}
+	if got := records[0].Header.Get("traceparent"); got != "00-trace-span-01" {
+		t.Errorf("traceparent recorded as %q", got)
+	}
+	if msg.Header.Get("Authorization") != "Bearer secret" {
+		t.Error("redaction changed the message header")
+	}
+}
+
+func TestRecordReply(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	defer ns.Shutdown()
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	sdk, err := New(&Config{AgentURI: ns.ClientURL(), PluginID: "record-test", RecordFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.Close()
+	handled := make(chan *nats.Msg, 1)
+	if _, err := sdk.subscribe("record.greet", func(msg *nats.Msg) {
+		respond(msg, []byte(`{"greeting":"hello"}`))
+		handled <- msg
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	resp, err := nc.Request("record.greet", []byte(`{}`), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Data) != `{"greeting":"hello"}` {
+		t.Errorf("response %s", resp.Data)
+	}
+	// the handler sees the requester reply subject, not a recorder inbox
+	msg := <-handled
+	if !strings.HasPrefix(msg.Reply, nats.InboxPrefix) || msg.Reply != resp.Subject {
+		t.Errorf("handler reply subject %q, requester inbox %q", msg.Reply, resp.Subject)
+	}
+
+	records, err := ReadRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Direction != RecordIn || records[1].Direction != RecordReply {
+		t.Fatalf("records %+v", records)
+	}
+	if records[1].ID != records[0].ID || records[1].Subject != resp.Subject || string(records[1].Data) != `{"greeting":"hello"}` {
+		t.Errorf("reply record %+v", records[1])
+	}
+}
@@ -0,0 +1,196 @@
+package sdkv2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HandlerFunc handles an action request
+type HandlerFunc func(req *Request)
+
+// SubmitHandler handles a settings or requirements submission.
+// A non nil result is sent back as the reply unless the handler already replied.
+type SubmitHandler func(req *Request) any
+
+// Request wraps an inbound message addressed to a plugin
+type Request struct {
+	Msg      *nats.Msg
+	EntityID string // requester entity(spaceId), only set for bin.* plugins
+	Method   string
+	Headers  nats.Header
+	Registry map[string]any // the _registry of an action request
+	Body     map[string]any // the body of an action request, or the whole payload of a submission
+	plugin   *Plugin
+	claims   *Claims
+	job      *Job
+	replied  bool
+	span     trace.Span // span of the request handling, nil outside of plugin handlers
+}
+
+func newRequest(p *Plugin, msg *nats.Msg, method string) *Request {
+	req := &Request{
+		Msg:     msg,
+		Method:  method,
+		Headers: msg.Header,
+		plugin:  p,
+	}
+	if parts, ok := p.sdk.matchSubject(msg.Subject); ok {
+		req.EntityID = parts.EntityID
+	}
+	if len(msg.Data) > 0 {
+		content := models.ActionRequestContent{}
+		if err := sonic.Unmarshal(msg.Data, &content); err == nil {
+			req.Registry = content.Registry
+			req.Body = content.Body
+		}
+		if req.Registry == nil && req.Body == nil {
+			// submissions carry the form data directly
+			sonic.Unmarshal(msg.Data, &req.Body)
+		}
+	}
+	return req
+}
+
+// NewRequest wraps msg for the plugin its subject is addressed to
+func NewRequest(msg *nats.Msg) (*Request, error) {
+	p := GetPluginBySubject(msg.Subject)
+	if p == nil {
+		return nil, fmt.Errorf("no plugin registered for subject %s", msg.Subject)
+	}
+	parts, _ := p.sdk.matchSubject(msg.Subject)
+	rest := parts.Rest
+	if len(rest) > 1 && rest[len(rest)-1] == "@form" {
+		rest = rest[:len(rest)-1]
+	}
+	return newRequest(p, msg, strings.Join(rest, ".")), nil
+}
+
+// Plugin returns the plugin the request is addressed to
+func (r *Request) Plugin() *Plugin {
+	return r.plugin
+}
+
+// PluginID returns the ID of the plugin the request is addressed to
+func (r *Request) PluginID() string {
+	return r.plugin.ID()
+}
+
+// Subject returns the subject the request arrived on
+func (r *Request) Subject() string {
+	return r.Msg.Subject
+}
+
+// Header returns the first value of a request header
+func (r *Request) Header(key string) string {
+	return r.Headers.Get(key)
+}
+
+// Claims returns the claims of the request Authorization header, nil when absent or malformed.
+// When auth public keys are configured the claims are verified before any handler runs,
+// otherwise they are decoded as is and Claims.Verified is false.
+func (r *Request) Claims() *Claims {
+	if r.claims != nil {
+		return r.claims
+	}
+	token := authToken(r.Header("Authorization"))
+	if token == "" {
+		return nil
+	}
+	claims, err := parseClaims(token, r.plugin.sdk.claimNames)
+	if err != nil {
+		return nil
+	}
+	r.claims = claims
+	return r.claims
+}
+
+// Bind decodes the request body into v
+func (r *Request) Bind(v any) error {
+	bodyByte, err := sonic.Marshal(r.Body)
+	if err != nil {
+		return err
+	}
+	return sonic.Unmarshal(bodyByte, v)
+}
+
+// Accept starts a job for the request and replies with its jobId.
+// Calling it again returns the same jobId.
+func (r *Request) Accept() string {
+	if r.job != nil {
+		return r.job.ID
+	}
+	jobId := r.plugin.Accept(r.Msg)
+	if jobId == "" {
+		return ""
+	}
+	r.replied = true
+	r.job = &Job{
+		ID:       jobId,
+		EntityID: r.EntityID,
+		Method:   r.Method,
+		plugin:   r.plugin,
+
+		correlationID: correlationID(r.Msg.Header),
+		traceID:       traceID(r.Msg.Header),
+	}
+	if r.span != nil && r.span.SpanContext().IsValid() {
+		// progress reports are traced as children of the request
+		r.job.spanContext = r.span.SpanContext()
+		r.job.traceID = r.span.SpanContext().TraceID().String()
+		r.plugin.jobTraces.Store(jobId, r.job.spanContext)
+	}
+	return jobId
+}
+
+// Job returns the job started by Accept, nil if the request was not accepted
+func (r *Request) Job() *Job {
+	return r.job
+}
+
+// Reject replies with an error carrying code and details
+func (r *Request) Reject(code ErrorCode, details map[string]any) {
+	r.Fail(NewError(code, "").WithDetails(details))
+}
+
+// Fail replies with err, errors that are not an *Error are reported as internal
+func (r *Request) Fail(err error) {
+	spanError(r.span, err)
+	r.plugin.rejectAction(r.Msg, r.Method, err)
+	r.replied = true
+}
+
+// Reply marshals v and sends it as the reply
+func (r *Request) Reply(v any) error {
+	body, err := sonic.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.replied = true
+	return respond(r.Msg, body)
+}
+
+// Replied reports whether a reply was already sent for the request
+func (r *Request) Replied() bool {
+	return r.replied
+}
+
+// MsgHandler adapts a func(*nats.Msg) action handler to a HandlerFunc
+func MsgHandler(handler func(msg *nats.Msg)) HandlerFunc {
+	return func(req *Request) {
+		handler(req.Msg)
+	}
+}
+
+// MsgSubmitHandler adapts a func(*nats.Msg) any submission handler to a SubmitHandler.
+// As before, the handler is expected to reply by itself and its result is ignored.
+func MsgSubmitHandler(handler func(msg *nats.Msg) any) SubmitHandler {
+	return func(req *Request) any {
+		handler(req.Msg)
+		return nil
+	}
+}
@@ -0,0 +1,232 @@
+package sdkv2
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// startRequestTest starts a NATS server and an SDK with a plugin registered under pluginID
+func startRequestTest(t *testing.T, pluginID string) *Plugin {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	t.Cleanup(ns.Shutdown)
+	sdk, err := New(&Config{AgentURI: ns.ClientURL(), PluginID: pluginID, DisableJobEvents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sdk.Close() })
+	return NewPlugin(sdk)
+}
+
+// roundTrip sends data to subject, handles the message with handle and returns the reply
+func roundTrip(t *testing.T, p *Plugin, subject string, data string, handle func(msg *nats.Msg)) map[string]any {
+	t.Helper()
+	sub, err := p.sdk.conn.Subscribe(subject, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	resp, err := p.sdk.conn.Request(subject, []byte(data), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := map[string]any{}
+	if err := json.Unmarshal(resp.Data, &reply); err != nil {
+		t.Fatalf("reply %s: %v", resp.Data, err)
+	}
+	return reply
+}
+
+// replyError returns details.error of a reply
+func replyError(reply map[string]any) map[string]any {
+	details, _ := reply["details"].(map[string]any)
+	e, _ := details["error"].(map[string]any)
+	return e
+}
+
+func TestNewRequest(t *testing.T) {
+	startRequestTest(t, "bin.*.request-test")
+	tests := []struct {
+		name     string
+		subject  string
+		data     string
+		err      bool
+		method   string
+		entityID string
+		body     string // JSON of the body
+		registry string // JSON of the registry
+	}{
+		{
+			name:     "action",
+			subject:  "soren.cpu.bin.space-1.request-test.scan",
+			data:     `{"_registry": {"idempotencyKey": "k"}, "body": {"depth": 2}}`,
+			method:   "scan",
+			entityID: "space-1",
+			body:     `{"depth":2}`,
+			registry: `{"idempotencyKey":"k"}`,
+		},
+		{
+			name:     "form",
+			subject:  "soren.v2.bin.space-2.request-test.scan.@form",
+			method:   "scan",
+			entityID: "space-2",
+			body:     `null`,
+			registry: `null`,
+		},
+		{
+			name:     "submission",
+			subject:  "soren.v2.bin.space-1.request-test.@settings",
+			data:     `{"token": "t"}`,
+			method:   "@settings",
+			entityID: "space-1",
+			body:     `{"token":"t"}`,
+			registry: `null`,
+		},
+		{
+			name:    "unknown plugin",
+			subject: "soren.cpu.other.scan",
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewRequest(&nats.Msg{Subject: tt.subject, Data: []byte(tt.data)})
+			if tt.err {
+				if err == nil {
+					t.Fatal("no error for an unregistered subject")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := json.Marshal(req.Body)
+			registry, _ := json.Marshal(req.Registry)
+			if req.Method != tt.method || req.EntityID != tt.entityID || string(body) != tt.body || string(registry) != tt.registry {
+				t.Errorf("request %s %s body %s registry %s", req.Method, req.EntityID, body, registry)
+			}
+			if req.PluginID() != "bin.*.request-test" || req.Subject() != tt.subject {
+				t.Errorf("plugin %s subject %s", req.PluginID(), req.Subject())
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	target := struct {
+		Depth int    `json:"depth"`
+		Path  string `json:"path"`
+	}{}
+	req := &Request{Body: map[string]any{"depth": 2, "path": "/tmp"}}
+	if err := req.Bind(&target); err != nil || target.Depth != 2 || target.Path != "/tmp" {
+		t.Errorf("bound %+v, error %v", target, err)
+	}
+	if err := (&Request{Body: map[string]any{"depth": "deep"}}).Bind(&target); err == nil {
+		t.Error("no error binding a string to an int")
+	}
+	if err := (&Request{Body: map[string]any{"depth": make(chan int)}}).Bind(&target); err == nil {
+		t.Error("no error binding a body that does not marshal")
+	}
+}
+
+func TestRequestReplies(t *testing.T) {
+	p := startRequestTest(t, "reply-test")
+	const subject = "soren.cpu.reply-test.scan"
+	handle := func(f func(req *Request)) func(msg *nats.Msg) {
+		return func(msg *nats.Msg) {
+			req, err := NewRequest(msg)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			f(req)
+		}
+	}
+
+	t.Run("accept", func(t *testing.T) {
+		var first, second string
+		var replied bool
+		reply := roundTrip(t, p, subject, `{}`, handle(func(req *Request) {
+			first = req.Accept()
+			second = req.Accept()
+			replied = req.Replied()
+		}))
+		if first == "" || first != second || reply["jobId"] != first || !replied {
+			t.Errorf("accepted %q then %q, replied %v, reply %v", first, second, replied, reply)
+		}
+	})
+	t.Run("reject", func(t *testing.T) {
+		reply := roundTrip(t, p, subject, `{}`, handle(func(req *Request) {
+			req.Reject(CodeInvalidInput, map[string]any{"field": "depth"})
+		}))
+		e := replyError(reply)
+		details, _ := e["details"].(map[string]any)
+		if e["code"] != string(CodeInvalidInput) || details["field"] != "depth" || reply["jobId"] != "" {
+			t.Errorf("reject reply %v", reply)
+		}
+	})
+	t.Run("fail", func(t *testing.T) {
+		reply := roundTrip(t, p, subject, `{}`, handle(func(req *Request) {
+			req.Fail(io.EOF)
+		}))
+		if e := replyError(reply); e["code"] != string(CodeInternal) || e["message"] != "EOF" || e["retryable"] != false {
+			t.Errorf("fail reply %v", reply)
+		}
+	})
+	t.Run("fail retryable", func(t *testing.T) {
+		reply := roundTrip(t, p, subject, `{}`, handle(func(req *Request) {
+			req.Fail(NewError(CodeUnavailable, "later"))
+		}))
+		if e := replyError(reply); e["code"] != string(CodeUnavailable) || e["retryable"] != true {
+			t.Errorf("fail reply %v", reply)
+		}
+	})
+	t.Run("reply", func(t *testing.T) {
+		var replied bool
+		reply := roundTrip(t, p, subject, `{}`, handle(func(req *Request) {
+			if req.Replied() {
+				t.Error("replied before the reply")
+			}
+			if err := req.Reply(make(chan int)); err == nil || req.Replied() {
+				t.Errorf("a reply that does not marshal: error %v, replied %v", err, req.Replied())
+			}
+			req.Reply(map[string]any{"status": "ok"})
+			replied = req.Replied()
+		}))
+		if reply["status"] != "ok" || !replied {
+			t.Errorf("reply %v, replied %v", reply, replied)
+		}
+	})
+}
+
+func TestMsgHandlerAdapters(t *testing.T) {
+	msg := &nats.Msg{Subject: "soren.cpu.p.scan"}
+	req := &Request{Msg: msg}
+	var got *nats.Msg
+	MsgHandler(func(m *nats.Msg) { got = m })(req)
+	if got != msg {
+		t.Error("MsgHandler did not pass the request message")
+	}
+
+	got = nil
+	result := MsgSubmitHandler(func(m *nats.Msg) any {
+		got = m
+		return errors.New("ignored")
+	})(req)
+	if got != msg || result != nil {
+		t.Errorf("MsgSubmitHandler passed %v and returned %v", got, result)
+	}
+}
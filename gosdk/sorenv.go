@@ -2,12 +2,23 @@ package sdkv2
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/base64"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	nats "github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/logtool"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
 )
 
 // SorenSDK represents the main SDK instance for Soren v2 protocol
@@ -18,8 +29,30 @@ type SorenSDK struct {
 	authKey      string
 	eventChannel string
 	storeChannel string
+	authKeys     []ed25519.PublicKey
+	claimNames   claimNames
+	idempotency  time.Duration
 	ctx          context.Context
 	cancel       context.CancelFunc
+	dryRun       bool
+	recorder     *recorder
+	strictForms  bool
+
+	disableJobEvents bool
+	eventSubjectFunc EventSubjectFunc
+	mutex            sync.Mutex
+	eventQueues      []*eventQueue
+	spool            *eventSpool
+	spoolWake        chan struct{} // replays the spool before the next retry tick
+	tracer           trace.Tracer
+	tracerProvider   *sdktrace.TracerProvider // set when the SDK created it
+	propagator       propagation.TextMapPropagator
+	metrics          *sdkMetrics
+	metricsServer    *http.Server
+	metricsListener  net.Listener
+	reportStop       chan struct{}  // closed to stop the metrics reporter
+	reporting        sync.WaitGroup // the metrics reporter
+	logCore          zapcore.Core   // teed into logtool, set with LogEventLevel
 }
 
 // Config holds the configuration for the Soren SDK
@@ -30,6 +63,54 @@ type Config struct {
 	AuthKey      string
 	EventChannel string
 	StoreChannel string
+	// AuthPublicKeys enables verification of inbound Authorization tokens.
+	// Each key is a PEM encoded or base64 raw ed25519 public key.
+	AuthPublicKeys []string
+	// RolesClaim and ScopesClaim name the token claims that Claims.Roles and Claims.Scopes, and so
+	// action permissions, are read from, DefaultRolesClaim and DefaultScopesClaim when empty.
+	// A dotted name reaches into nested claims, e.g. realm_access.roles.
+	RolesClaim  string
+	ScopesClaim string
+	// IdempotencyWindow is how long a retried request gets the jobId of its first accept back,
+	// DefaultIdempotencyWindow when zero, disabled when negative
+	IdempotencyWindow time.Duration
+	// DryRun skips the NATS connection, Plugin.Start then writes the @intro, @settings and
+	// @actions replies to SOREN_DRY_RUN_OUTPUT (stdout when unset) and returns
+	DryRun bool
+	// RecordFile enables recording of every message the SDK receives and sends,
+	// appended as JSONL records that ReadRecords reads back.
+	// Replies are recorded when the handler sends them through the SDK, not with msg.Respond.
+	RecordFile string
+	// StrictForms makes Plugin.Start fail on the problems Plugin.LintForms finds instead of logging them
+	StrictForms bool
+	// EventSubject routes events to subjects by type, e.g. EventSubjectByType.
+	// When nil events go to <channel>.log, and batches to <channel>.<plugin ID>.log
+	EventSubject EventSubjectFunc
+	// TracerProvider traces requests, the global OpenTelemetry provider when nil
+	TracerProvider trace.TracerProvider
+	// TraceExporter sends spans to an exporter through a tracer provider the SDK shuts down on Close.
+	// SOREN_TRACE_EXPORTER=stdout prints them.
+	TraceExporter sdktrace.SpanExporter
+	// DisableJobEvents stops plugins from sending job lifecycle events to the event channel
+	DisableJobEvents bool
+	// EventSpoolDir enables the event spool: events that cannot be delivered are kept in segment files
+	// in this directory and sent in order once the agent is reachable again. Spooled batches the
+	// platform rejects are moved to dead-letter.jsonl in the directory.
+	EventSpoolDir string
+	// EventSpoolSize is the disk space the spool uses at most, DefaultEventSpoolSize when zero.
+	// The oldest segments are dropped past it.
+	EventSpoolSize int64
+	// EventSpoolSegmentSize is the size of a spool segment file, DefaultEventSpoolSegmentSize when zero
+	EventSpoolSegmentSize int64
+	// MetricsAddr starts an HTTP listener serving the SDK metrics on /metrics in the Prometheus text format,
+	// e.g. "127.0.0.1:9464"
+	MetricsAddr string
+	// MetricsInterval sends the SDK metrics as metric events to the event channel at this interval,
+	// off when zero
+	MetricsInterval time.Duration
+	// LogEventLevel ships the logtool logs at or above this level, e.g. "warn", to the event channel
+	// as log events, see NewEventCore
+	LogEventLevel string
 }
 
 // New creates a new Soren SDK instance
@@ -57,31 +138,72 @@ func New(config *Config) (*SorenSDK, error) {
 	if config.AgentCred == "" {
 		config.AgentCred = os.Getenv("AGENT_CRED")
 	}
+	if len(config.AuthPublicKeys) == 0 && os.Getenv("SOREN_AUTH_PUBLIC_KEYS") != "" {
+		config.AuthPublicKeys = strings.Split(os.Getenv("SOREN_AUTH_PUBLIC_KEYS"), ",")
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = os.Getenv("SOREN_ROLES_CLAIM")
+	}
+	if config.ScopesClaim == "" {
+		config.ScopesClaim = os.Getenv("SOREN_SCOPES_CLAIM")
+	}
+	if !config.DryRun {
+		config.DryRun, _ = strconv.ParseBool(os.Getenv("SOREN_DRY_RUN"))
+	}
+	if !config.StrictForms {
+		config.StrictForms, _ = strconv.ParseBool(os.Getenv("SOREN_STRICT_FORMS"))
+	}
+	if config.EventSpoolDir == "" {
+		config.EventSpoolDir = os.Getenv("SOREN_EVENT_SPOOL_DIR")
+	}
+	if config.RecordFile == "" {
+		config.RecordFile = os.Getenv("SOREN_RECORD_FILE")
+	}
+	if config.LogEventLevel == "" {
+		config.LogEventLevel = os.Getenv("SOREN_LOG_EVENT_LEVEL")
+	}
+	if config.MetricsAddr == "" {
+		config.MetricsAddr = os.Getenv("SOREN_METRICS_ADDR")
+	}
+	if config.MetricsInterval == 0 && os.Getenv("SOREN_METRICS_INTERVAL") != "" {
+		interval, err := time.ParseDuration(os.Getenv("SOREN_METRICS_INTERVAL"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOREN_METRICS_INTERVAL: %w", err)
+		}
+		config.MetricsInterval = interval
+	}
+	if config.DryRun && config.PluginID == "" {
+		config.PluginID = "dry-run"
+	}
 	// Validate required configuration
-	if config.AgentURI == "" {
+	if config.AgentURI == "" && !config.DryRun {
 		return nil, fmt.Errorf("agent URI is required")
 	}
 	if config.PluginID == "" {
 		return nil, fmt.Errorf("plugin ID is required")
 	}
+	authKeys := make([]ed25519.PublicKey, 0, len(config.AuthPublicKeys))
+	for _, key := range config.AuthPublicKeys {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+		publicKey, err := parsePublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth public key: %w", err)
+		}
+		authKeys = append(authKeys, publicKey)
+	}
 	var nc *nats.Conn
 	var err error
-	// Connect to NATS
-	if config.AgentCred != "" {
-		if strings.HasPrefix(config.AgentCred, "-----BEGIN") {
-			nc, err = nats.Connect(config.AgentURI, nats.UserCredentialBytes([]byte(config.AgentCred)))
-		}
-		credByte, err := base64.StdEncoding.DecodeString(config.AgentCred)
+	// Connect to NATS, a dry run only describes the plugin
+	if !config.DryRun {
+		nc, err = connect(config)
 		if err != nil {
 			return nil, err
 		}
-		nc, err = nats.Connect(config.AgentURI, nats.UserCredentialBytes([]byte(credByte)))
-
-	} else {
-		nc, err = nats.Connect(config.AgentURI)
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	if config.IdempotencyWindow == 0 {
+		config.IdempotencyWindow = DefaultIdempotencyWindow
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -92,13 +214,90 @@ func New(config *Config) (*SorenSDK, error) {
 		authKey:      config.AuthKey,
 		eventChannel: config.EventChannel,
 		storeChannel: config.StoreChannel,
+		authKeys:     authKeys,
+		claimNames:   claimNames{roles: config.RolesClaim, scopes: config.ScopesClaim},
+		idempotency:  config.IdempotencyWindow,
 		ctx:          ctx,
 		cancel:       cancel,
+		dryRun:       config.DryRun,
+		strictForms:  config.StrictForms,
+	}
+	sdk.disableJobEvents = config.DisableJobEvents
+	sdk.metrics = newSDKMetrics()
+	sdk.eventSubjectFunc = config.EventSubject
+	if err := sdk.setupTracing(config); err != nil {
+		cancel()
+		if nc != nil {
+			nc.Close()
+		}
+		return nil, err
+	}
+	if config.RecordFile != "" && nc != nil {
+		if err := sdk.startRecording(config.RecordFile); err != nil {
+			cancel()
+			nc.Close()
+			return nil, err
+		}
+	}
+	if config.EventSpoolDir != "" && nc != nil {
+		spool, err := openEventSpool(config.EventSpoolDir, config.EventSpoolSize, config.EventSpoolSegmentSize)
+		if err != nil {
+			cancel()
+			nc.Close()
+			return nil, err
+		}
+		sdk.spool = spool
+		sdk.spoolWake = make(chan struct{}, 1)
+		nc.SetReconnectHandler(func(*nats.Conn) {
+			sdk.wakeSpool()
+		})
+		go sdk.retrySpool()
+	}
+	if config.MetricsAddr != "" && nc != nil {
+		if err := sdk.serveMetrics(config.MetricsAddr); err != nil {
+			sdk.Close()
+			return nil, err
+		}
+	}
+	if config.LogEventLevel != "" && config.EventChannel != "" && nc != nil {
+		level, err := zapcore.ParseLevel(config.LogEventLevel)
+		if err != nil {
+			sdk.Close()
+			return nil, fmt.Errorf("invalid log event level: %w", err)
+		}
+		sdk.logCore = NewEventCore(NewAsyncEventLogger(sdk, AsyncOptions{}), level)
+		logtool.AddCore(sdk.logCore)
+	}
+	if config.MetricsInterval > 0 && config.EventChannel != "" && nc != nil {
+		sdk.startMetricsReporter(config.MetricsInterval)
 	}
 
 	return sdk, nil
 }
 
+// connect opens the NATS connection to the agent
+func connect(config *Config) (*nats.Conn, error) {
+	var nc *nats.Conn
+	var err error
+	if config.AgentCred != "" {
+		if strings.HasPrefix(config.AgentCred, "-----BEGIN") {
+			nc, err = nats.Connect(config.AgentURI, nats.UserCredentialBytes([]byte(config.AgentCred)))
+		}
+		credByte, err := base64.StdEncoding.DecodeString(config.AgentCred)
+		if err != nil {
+			return nil, err
+		}
+		nc, err = nats.Connect(config.AgentURI, nats.UserCredentialBytes([]byte(credByte)))
+
+	} else {
+		nc, err = nats.Connect(config.AgentURI)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return nc, nil
+}
+
 // NewFromEnv creates a new Soren SDK instance using environment variables
 func NewFromEnv() (*SorenSDK, error) {
 	return New(nil)
@@ -106,11 +305,70 @@ func NewFromEnv() (*SorenSDK, error) {
 
 // Close closes the SDK connection and cleans up resources
 func (s *SorenSDK) Close() error {
+	GetPluginHolder().removeSDK(s)
+	if s.logCore != nil {
+		logtool.RemoveCore(s.logCore)
+	}
+	s.stopMetricsReporter()
+	// asynchronous event loggers send what they buffered while the connection is up
+	s.mutex.Lock()
+	queues := s.eventQueues
+	s.eventQueues = nil
+	s.mutex.Unlock()
+	for _, q := range queues {
+		q.close()
+	}
 	s.cancel()
-	s.conn.Close()
+	s.closeMetrics()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if s.spool != nil {
+		s.spool.close()
+	}
+	if s.tracerProvider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.tracerProvider.Shutdown(ctx)
+	}
+	if s.recorder != nil {
+		return s.recorder.close()
+	}
 	return nil
 }
 
+// retrySpool replays the event spool, left over from a previous run or
+// filled by timeouts that did not drop the connection, until the SDK closes.
+// A reconnect replays it right away.
+func (s *SorenSDK) retrySpool() {
+	ticker := time.NewTicker(spoolRetryInterval)
+	defer ticker.Stop()
+	s.replaySpool()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.spoolWake:
+		}
+		s.replaySpool()
+	}
+}
+
+// wakeSpool makes retrySpool replay the spool now
+func (s *SorenSDK) wakeSpool() {
+	select {
+	case s.spoolWake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *SorenSDK) addEventQueue(q *eventQueue) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.eventQueues = append(s.eventQueues, q)
+}
+
 // GetConnection returns the underlying NATS connection
 func (s *SorenSDK) GetConnection() *nats.Conn {
 	return s.conn
@@ -146,6 +404,43 @@ func (s *SorenSDK) makeSubject(action string) string {
 	return fmt.Sprintf("soren.v2.%s.%s", s.pluginID, action)
 }
 
+// subjectParts are the pieces of a subject addressed to a plugin
+type subjectParts struct {
+	Prefix   string   // "v2" or "cpu"
+	EntityID string   // requester entity(spaceId), only set for bin.* plugins
+	Rest     []string // tokens after the plugin ID
+}
+
+// matchSubject reports whether subject is addressed to this plugin on either
+// the soren.v2 or soren.cpu prefix. For bin.* plugins the entity id that the
+// gateway put in place of the wildcard is returned as well.
+func (s *SorenSDK) matchSubject(subject string) (subjectParts, bool) {
+	parts := subjectParts{}
+	tokens := strings.Split(subject, ".")
+	if len(tokens) < 3 || tokens[0] != "soren" || (tokens[1] != "v2" && tokens[1] != "cpu") {
+		return parts, false
+	}
+	idTokens := strings.Split(s.pluginID, ".")
+	if len(tokens) <= 2+len(idTokens) {
+		return parts, false
+	}
+	for i, t := range idTokens {
+		got := tokens[2+i]
+		if t == "*" {
+			if parts.EntityID == "" {
+				parts.EntityID = got
+			}
+			continue
+		}
+		if got != t {
+			return subjectParts{}, false
+		}
+	}
+	parts.Prefix = tokens[1]
+	parts.Rest = tokens[2+len(idTokens):]
+	return parts, true
+}
+
 // makeSettingsSubject creates a subject with the soren.v2 prefix
 func (s *SorenSDK) makeSettingsSubject() string {
 	return fmt.Sprintf("soren.v2.%s.@settings", s.pluginID)
@@ -171,7 +466,6 @@ func (s *SorenSDK) makeJobSubject(jobID, jobUpdate string) string {
 	return fmt.Sprintf("soren.cpu.%s.%s.%s", s.pluginID, jobID, jobUpdate)
 }
 
-
 // makeFormSubject creates a subject for form requests
 func (s *SorenSDK) makeFormSubject(action string) string {
 	return fmt.Sprintf("soren.v2.%s.%s.@form", s.pluginID, action)
@@ -0,0 +1,278 @@
+package sdkv2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultEventSpoolSize is the disk space the event spool uses at most
+	DefaultEventSpoolSize = 64 << 20
+	// DefaultEventSpoolSegmentSize is the size a spool segment file grows to before the next one is started
+	DefaultEventSpoolSegmentSize = 4 << 20
+
+	spoolSuffix        = ".spool"
+	spoolRetryInterval = 10 * time.Second
+	// deadLetterFile keeps the spooled batches the platform rejected, up to a segment size
+	deadLetterFile = "dead-letter.jsonl"
+)
+
+// errEventsRejected is returned for a batch the platform answered with an error.
+// A rejected spooled batch is moved to the dead-letter file instead of being sent again.
+var errEventsRejected = errors.New("event sending failed")
+
+// spooledBatch is a line of a spool segment, a batch of events and the subject it goes to
+type spooledBatch struct {
+	Subject string          `json:"subject"`
+	Events  json.RawMessage `json:"events"`
+	Error   string          `json:"error,omitempty"` // why the platform rejected it, in the dead-letter file
+}
+
+// eventSpool keeps the events that could not be delivered in segment files, oldest first
+type eventSpool struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	mutex    sync.Mutex
+	file     *os.File // segment being appended to
+	fileSize int64
+	seq      uint64
+	sizes    map[string]int64 // segment path to size
+	// replaying lets a single replay run at a time
+	replaying sync.Mutex
+	replayed  string // segment being replayed, trim keeps it
+}
+
+func openEventSpool(dir string, maxSize, segmentSize int64) (*eventSpool, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultEventSpoolSize
+	}
+	if segmentSize <= 0 {
+		segmentSize = DefaultEventSpoolSegmentSize
+	}
+	segmentSize = min(segmentSize, maxSize)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create event spool: %w", err)
+	}
+	s := &eventSpool{dir: dir, maxSize: maxSize, segmentSize: segmentSize, sizes: map[string]int64{}}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event spool: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolSuffix) {
+			continue
+		}
+		var seq uint64
+		if _, err := fmt.Sscanf(name, "%020d"+spoolSuffix, &seq); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		s.sizes[filepath.Join(dir, name)] = info.Size()
+		s.seq = max(s.seq, seq)
+	}
+	return s, nil
+}
+
+// pending reports whether events wait in the spool
+func (s *eventSpool) pending() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.sizes) > 0
+}
+
+// append adds a batch to the newest segment, dropping the oldest segments past the size limit
+func (s *eventSpool) append(subject string, body []byte) error {
+	line, err := json.Marshal(spooledBatch{Subject: subject, Events: body})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil || s.fileSize+int64(len(line)) > s.segmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.Write(line); err != nil {
+		return fmt.Errorf("failed to spool events: %w", err)
+	}
+	s.fileSize += int64(len(line))
+	s.sizes[s.file.Name()] = s.fileSize
+	s.trim()
+	return nil
+}
+
+// rotate starts a new segment
+func (s *eventSpool) rotate() error {
+	s.closeSegment()
+	s.seq++
+	file, err := os.OpenFile(filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.seq, spoolSuffix)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	s.file = file
+	s.fileSize = 0
+	s.sizes[file.Name()] = 0
+	return nil
+}
+
+func (s *eventSpool) closeSegment() {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+}
+
+// trim removes the oldest segments while the spool is over its size limit
+func (s *eventSpool) trim() {
+	total := int64(0)
+	for _, size := range s.sizes {
+		total += size
+	}
+	for _, path := range s.segments() {
+		if total <= s.maxSize {
+			return
+		}
+		if (s.file != nil && path == s.file.Name()) || path == s.replayed {
+			continue
+		}
+		eventsLogger().Warnw("event spool full, dropping segment", "segment", filepath.Base(path))
+		os.Remove(path)
+		total -= s.sizes[path]
+		delete(s.sizes, path)
+	}
+}
+
+// segments returns the segment paths, oldest first
+func (s *eventSpool) segments() []string {
+	paths := make([]string, 0, len(s.sizes))
+	for path := range s.sizes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// replay sends the spooled batches in order with send, until the spool is empty or send fails.
+// Batches that send delivered are removed from the spool.
+func (s *eventSpool) replay(send func(subject string, body []byte) error) error {
+	s.replaying.Lock()
+	defer s.replaying.Unlock()
+	for {
+		s.mutex.Lock()
+		segments := s.segments()
+		if len(segments) == 0 {
+			s.mutex.Unlock()
+			return nil
+		}
+		path := segments[0]
+		// new events go to a new segment while this one is replayed
+		if s.file != nil && s.file.Name() == path {
+			s.closeSegment()
+		}
+		s.replayed = path
+		s.mutex.Unlock()
+
+		err := s.replaySegment(path, send)
+		s.mutex.Lock()
+		s.replayed = ""
+		s.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *eventSpool) replaySegment(path string, send func(subject string, body []byte) error) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		s.remove(path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read spool segment: %w", err)
+	}
+	// lines are read whole, a batch may be larger than a segment
+	reader := bufio.NewReader(bytes.NewReader(data))
+	offset := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			break
+		}
+		batch := spooledBatch{}
+		if err := json.Unmarshal(line, &batch); err == nil {
+			err := send(batch.Subject, batch.Events)
+			if errors.Is(err, errEventsRejected) {
+				// sending it again would be rejected again and hold back the batches behind it
+				s.deadLetter(batch, err)
+				err = nil
+			}
+			if err != nil {
+				// keep what is left for the next replay
+				s.mutex.Lock()
+				defer s.mutex.Unlock()
+				rest := data[offset:]
+				if werr := os.WriteFile(path+".tmp", rest, 0o644); werr == nil && os.Rename(path+".tmp", path) == nil {
+					s.sizes[path] = int64(len(rest))
+				}
+				return err
+			}
+		}
+		offset += len(line)
+	}
+	s.remove(path)
+	return nil
+}
+
+// deadLetter appends a rejected batch to the dead-letter file, it is dropped once the file reaches a segment size
+func (s *eventSpool) deadLetter(batch spooledBatch, err error) {
+	batch.Error = err.Error()
+	line, merr := json.Marshal(batch)
+	if merr != nil {
+		return
+	}
+	line = append(line, '\n')
+	path := filepath.Join(s.dir, deadLetterFile)
+	if info, serr := os.Stat(path); serr == nil && info.Size()+int64(len(line)) > s.segmentSize {
+		eventsLogger().Warnw("event dead-letter file full, dropping rejected batch", "subject", batch.Subject, "error", err)
+		return
+	}
+	file, ferr := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if ferr != nil {
+		eventsLogger().Warnw("failed to open event dead-letter file", "error", ferr)
+		return
+	}
+	defer file.Close()
+	file.Write(line)
+	eventsLogger().Warnw("spooled events rejected, moved to the dead-letter file", "subject", batch.Subject, "error", err)
+}
+
+func (s *eventSpool) remove(path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	os.Remove(path)
+	delete(s.sizes, path)
+}
+
+func (s *eventSpool) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closeSegment()
+}
@@ -0,0 +1,272 @@
+package sdkv2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+)
+
+func TestEventSpool(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	defer ns.Shutdown()
+	dir := t.TempDir()
+	sdk, err := New(&Config{AgentURI: ns.ClientURL(), PluginID: "spool-test", EventChannel: "events", EventSpoolDir: dir, EventSpoolSegmentSize: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.Close()
+	logger := NewEventLogger(sdk)
+
+	// nobody listens on the event channel, events go to the spool
+	for i := range 5 {
+		if err := logger.Log("test", models.LogLevelInfo, fmt.Sprint(i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if segments, _ := os.ReadDir(dir); len(segments) < 2 {
+		t.Fatalf("expected several segments, got %d", len(segments))
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	received := []string{}
+	nc.Subscribe("events.>", func(msg *nats.Msg) {
+		events := []models.PluginEvent{}
+		json.Unmarshal(msg.Data, &events)
+		for _, event := range events {
+			received = append(received, event.Message)
+		}
+		msg.Respond([]byte(`{"result":"OK"}`))
+	})
+	nc.Flush()
+
+	// the agent is back but older events wait, this one queues behind them
+	logger.Log("test", models.LogLevelInfo, "5", nil)
+	if len(received) != 0 {
+		t.Fatalf("event sent ahead of the spool: %v", received)
+	}
+	sdk.replaySpool()
+	if fmt.Sprint(received) != "[0 1 2 3 4 5]" {
+		t.Fatalf("replayed %v", received)
+	}
+	if sdk.spool.pending() {
+		t.Fatal("spool not empty after replay")
+	}
+	logger.Log("test", models.LogLevelInfo, "6", nil)
+	if len(received) != 7 {
+		t.Fatalf("event not sent directly: %v", received)
+	}
+}
+
+func TestEventSpoolSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := openEventSpool(dir, 500, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(`[{"message":"0123456789012345678901234567890123456789"}]`)
+	for range 20 {
+		if err := spool.append("events.log", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spool.close()
+	total := int64(0)
+	segments, _ := os.ReadDir(dir)
+	for _, segment := range segments {
+		info, _ := segment.Info()
+		total += info.Size()
+	}
+	if total > 500 {
+		t.Fatalf("spool uses %d bytes, limit is 500", total)
+	}
+	// a reopened spool picks up where it was
+	spool, err = openEventSpool(dir, 500, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := 0
+	spool.replay(func(subject string, body []byte) error {
+		sent++
+		return nil
+	})
+	if sent == 0 || spool.pending() {
+		t.Fatalf("reopened spool replayed %d batches, pending %v", sent, spool.pending())
+	}
+}
+
+func TestEventSpoolLargeBatch(t *testing.T) {
+	spool, err := openEventSpool(t.TempDir(), 1<<20, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := []byte(`[{"message":"` + strings.Repeat("x", 100<<10) + `"}]`)
+	for _, body := range [][]byte{[]byte(`[{"message":"before"}]`), large, []byte(`[{"message":"after"}]`)} {
+		if err := spool.append("events.log", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := []int{}
+	if err := spool.replay(func(subject string, body []byte) error {
+		sent = append(sent, len(body))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 3 || sent[1] != len(large) {
+		t.Fatalf("replayed batches of %v bytes, want the large batch between two others", sent)
+	}
+}
+
+func TestEventSpoolTrimDuringReplay(t *testing.T) {
+	spool, err := openEventSpool(t.TempDir(), 500, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(`[{"message":"0123456789012345678901234567890123456789"}]`)
+	spool.append("events.log", body)
+	spool.append("events.log", body)
+	replayed := spool.segments()[0]
+	calls := 0
+	spool.replay(func(subject string, body []byte) error {
+		calls++
+		if calls == 1 {
+			// the spool overflows while its oldest segment is replayed
+			for range 20 {
+				spool.append("events.log", body)
+			}
+			if _, err := os.Stat(replayed); err != nil {
+				t.Errorf("segment being replayed was trimmed: %v", err)
+			}
+			return nil
+		}
+		return errors.New("agent gone")
+	})
+	if _, err := os.Stat(replayed); err != nil {
+		t.Fatalf("undelivered batch lost: %v", err)
+	}
+}
+
+func TestEventSpoolDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := openEventSpool(dir, 1<<20, 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"1", "2", "3"} {
+		spool.append("events.log", []byte(`[{"message":"`+message+`"}]`))
+	}
+	sent := []string{}
+	err = spool.replay(func(subject string, body []byte) error {
+		if strings.Contains(string(body), `"2"`) {
+			return fmt.Errorf("%w: INVALID", errEventsRejected)
+		}
+		sent = append(sent, string(body))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || spool.pending() {
+		t.Fatalf("sent %v, pending %v: the rejected batch held back the others", sent, spool.pending())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, deadLetterFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := spooledBatch{}
+	if err := json.Unmarshal(data, &batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Subject != "events.log" || string(batch.Events) != `[{"message":"2"}]` || !strings.Contains(batch.Error, "INVALID") {
+		t.Errorf("dead letter %s", data)
+	}
+
+	// the dead-letter file is not a segment, a reopened spool does not replay it
+	spool, err = openEventSpool(dir, 1<<20, 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spool.pending() {
+		t.Error("dead-letter file read as a segment")
+	}
+}
+
+func TestEventSpoolReplayOnReconnect(t *testing.T) {
+	opts := &server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true}
+	ns, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	port := ns.Addr().(*net.TCPAddr).Port
+	sdk, err := New(&Config{AgentURI: ns.ClientURL(), PluginID: "reconnect-test", EventChannel: "events", EventSpoolDir: t.TempDir(), DisableJobEvents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.Close()
+	ns.Shutdown()
+	for sdk.conn.IsConnected() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := NewEventLogger(sdk).Log("test", models.LogLevelInfo, "offline", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !sdk.spool.pending() {
+		t.Fatal("event logged offline not spooled")
+	}
+
+	// the agent comes back on the same address
+	opts.Port = port
+	ns, err = server.NewServer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan string, 1)
+	go ns.Start()
+	defer ns.Shutdown()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not restart")
+	}
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	nc.Subscribe("events.>", func(msg *nats.Msg) {
+		received <- string(msg.Data)
+		msg.Respond([]byte(`{"result":"OK"}`))
+	})
+	nc.Flush()
+	select {
+	case data := <-received:
+		if !strings.Contains(data, "offline") {
+			t.Errorf("replayed %s", data)
+		}
+	case <-time.After(spoolRetryInterval / 2):
+		t.Fatal("spool not replayed on reconnect")
+	}
+}
@@ -0,0 +1,109 @@
+package sdkv2
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/sorenhq/go-plugin-sdk/gosdk"
+
+// headerCarrier lets OpenTelemetry propagators read and write NATS headers
+type headerCarrier nats.Header
+
+func (c headerCarrier) Get(key string) string {
+	return nats.Header(c).Get(key)
+}
+
+func (c headerCarrier) Set(key, value string) {
+	nats.Header(c).Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// setupTracing picks the tracer provider: the configured one, one exporting to the configured
+// exporter or to stdout with SOREN_TRACE_EXPORTER=stdout, the global one otherwise
+func (s *SorenSDK) setupTracing(config *Config) error {
+	s.propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	provider := config.TracerProvider
+	exporter := config.TraceExporter
+	if provider == nil && exporter == nil {
+		switch name := os.Getenv("SOREN_TRACE_EXPORTER"); name {
+		case "", "none":
+		case "stdout":
+			var err error
+			exporter, err = stdouttrace.New()
+			if err != nil {
+				return fmt.Errorf("failed to create trace exporter: %w", err)
+			}
+		default:
+			return fmt.Errorf("unknown trace exporter %q", name)
+		}
+	}
+	if provider == nil && exporter != nil {
+		s.tracerProvider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", s.pluginID))),
+		)
+		provider = s.tracerProvider
+	}
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	s.tracer = provider.Tracer(tracerName)
+	return nil
+}
+
+// startSpan starts the span of an inbound message, child of the trace in its traceparent header
+func (s *SorenSDK) startSpan(msg *nats.Msg, name string) (context.Context, trace.Span) {
+	ctx := s.propagator.Extract(s.ctx, headerCarrier(msg.Header))
+	return s.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", msg.Subject),
+			attribute.String("soren.plugin.id", s.pluginID),
+		),
+	)
+}
+
+// injectTrace adds the trace headers of the span in ctx to msg
+func (s *SorenSDK) injectTrace(ctx context.Context, msg *nats.Msg) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	s.propagator.Inject(ctx, headerCarrier(msg.Header))
+}
+
+// remoteSpanContext returns the span context of the traceparent header of msg
+func (s *SorenSDK) remoteSpanContext(msg *nats.Msg) trace.SpanContext {
+	return trace.SpanContextFromContext(s.propagator.Extract(context.Background(), headerCarrier(msg.Header)))
+}
+
+// spanError marks span as failed with err
+func spanError(span trace.Span, err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,114 @@
+package sdkv2_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	sdkv2 "github.com/sorenhq/go-plugin-sdk/gosdk"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/gateway"
+	"github.com/sorenhq/go-plugin-sdk/gosdk/models"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	const channel = "soren.plugin.event.test"
+	gw, err := gateway.Start(gateway.Options{PluginID: "traced", EventChannel: channel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	sdk, err := sdkv2.New(&sdkv2.Config{AgentURI: gw.URL(), PluginID: "traced", EventChannel: channel, TracerProvider: provider, DisableJobEvents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := sdkv2.NewPlugin(sdk)
+	plugin.SetIntro(models.PluginIntro{Name: "Traced", Version: "1.0.0"}, nil)
+	plugin.AddActions([]models.Action{{Method: "run", Title: "Run"}})
+	logger := sdkv2.NewEventLogger(sdk)
+	done := make(chan struct{})
+	plugin.Handle("run", func(req *sdkv2.Request) {
+		req.Accept()
+		logger.WithContext(req.Context()).Log("run", models.LogLevelInfo, "traced", nil)
+		go func() {
+			defer close(done)
+			req.Job().Progress(models.JobProgress{Progress: 50})
+			req.Job().Done(nil)
+		}()
+	})
+	go plugin.Start()
+	if _, err := gw.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// outgoing progress reports and events carry the trace
+	var mutex sync.Mutex
+	traceparents := []string{}
+	subs := []*nats.Subscription{}
+	for _, subject := range []string{"soren.cpu.traced.>", channel + ".>"} {
+		sub, err := gw.Conn().Subscribe(subject, func(msg *nats.Msg) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			traceparents = append(traceparents, msg.Subject+" "+msg.Header.Get("traceparent"))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		subs = append(subs, sub)
+	}
+	gw.Conn().Flush()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	msg := nats.NewMsg("soren.cpu.traced.run")
+	msg.Data = []byte(`{"body":{}}`)
+	msg.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := gw.Conn().RequestMsg(msg, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	sdk.Close()
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	action, ok := spans["action run"]
+	if !ok {
+		t.Fatalf("no action span in %v", exporter.GetSpans())
+	}
+	if action.SpanContext.TraceID().String() != traceID || action.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("action span is not a child of the inbound trace: %+v", action.Parent)
+	}
+	progress, ok := spans["progress progress"]
+	if !ok {
+		t.Fatalf("no progress span in %v", exporter.GetSpans())
+	}
+	if progress.Parent.SpanID() != action.SpanContext.SpanID() {
+		t.Errorf("progress span is not a child of the action span")
+	}
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	seen := 0
+	for _, line := range traceparents {
+		subject, traceparent, _ := strings.Cut(line, " ")
+		if strings.HasPrefix(subject, "soren.cpu.traced.run") {
+			continue // the inbound request itself
+		}
+		if !strings.Contains(traceparent, traceID) {
+			t.Errorf("%s sent without the trace: %q", subject, traceparent)
+		}
+		seen++
+	}
+	if seen != 3 {
+		t.Errorf("saw %d traced messages, want 2 progress reports and 1 event", seen)
+	}
+}
@@ -1,7 +1,6 @@
 package sdkv2
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/bytedance/sonic"
@@ -11,37 +10,115 @@ import (
 )
 
 // Accept Request , make a request session and return sessionId - jobId
-func Accept(msg *nats.Msg) (jobId string) {
-	jobBody := models.JobBodyContent{}
+// A retry of an already accepted request gets the original jobId back.
+func (p *Plugin) Accept(msg *nats.Msg) (jobId string) {
 	uuid, err := uuid.NewV4()
 	if err != nil {
 		return ""
 	}
-	if strings.HasPrefix(GetPlugin().sdk.pluginID, "bin.*.") {
-		// Extract EntityId(spaceId) part after "bin.*."
-		parts := strings.Split(msg.Subject, ".")
-		if len(parts) >= 3 {
-			requesterSpaceId := parts[3]
-			GetjobsHolder().Add(uuid.String(), requesterSpaceId)
+	if jobId, existed := p.accepted.GetOrAdd(idempotencyKey(msg), uuid.String()); existed {
+		respondJobId(msg, jobId)
+		return jobId
+	}
+	event := models.JobEvent{JobID: uuid.String(), Status: models.JobStatusAccepted}
+	if parts, ok := p.sdk.matchSubject(msg.Subject); ok {
+		// bin.* plugins are addressed per requester entity(spaceId), progress must go back to it
+		if parts.EntityID != "" {
+			GetjobsHolder().Add(uuid.String(), parts.EntityID)
 		}
+		event.EntityID = parts.EntityID
+		event.Method = strings.Join(parts.Rest, ".")
 	}
-	jobBody.JobId = uuid.String()
-	responseByte, err := sonic.Marshal(jobBody)
-	if err != nil {
-		return ""
+	p.jobMethods.Store(uuid.String(), event.Method)
+	if err := respondJobId(msg, uuid.String()); err != nil {
+		p.jobLogger(uuid.String()).Errorw("accept respond error", "error", err)
+	}
+	p.sdk.metrics.jobStarted(uuid.String(), event.Method)
+	// Request.Accept replaces it with the span of the request handling
+	if sc := p.sdk.remoteSpanContext(msg); sc.IsValid() {
+		p.jobTraces.Store(uuid.String(), sc)
 	}
-	err = msg.Respond(responseByte)
-	
+	p.jobEvent(event, msg.Header)
 	return uuid.String()
 }
-func RejectWithBody(msg *nats.Msg, body map[string]any) {
+
+func respondJobId(msg *nats.Msg, jobId string) error {
+	responseByte, err := sonic.Marshal(models.JobBodyContent{JobId: jobId})
+	if err != nil {
+		return err
+	}
+	return respond(msg, responseByte)
+}
+
+// RejectWithBody replies to a request with body as details.error, without an error code.
+//
+// Deprecated: use Reject with an *Error, e.g. NewError(code, message).WithDetails(body).
+func (p *Plugin) RejectWithBody(msg *nats.Msg, body map[string]any) {
+	rejectWithBody(msg, body)
+}
+
+// Reject replies to a request with err, errors that are not an *Error are reported as internal
+func (p *Plugin) Reject(msg *nats.Msg, err error) {
+	method := msg.Subject
+	if parts, ok := p.sdk.matchSubject(msg.Subject); ok {
+		method = strings.Join(parts.Rest, ".")
+	}
+	p.rejectAction(msg, method, err)
+}
+
+// rejectAction replies to a request for method with err and counts the rejection
+func (p *Plugin) rejectAction(msg *nats.Msg, method string, err error) {
+	p.sdk.metrics.rejected(method, err)
+	reject(msg, err)
+}
+
+// Fail ends a job with err instead of a result
+func (p *Plugin) Fail(jobId string, err error) any {
+	return p.Progress(jobId, models.ProgressCommand, models.JobProgress{Progress: 100, Details: map[string]any{"error": AsError(err)}})
+}
+
+func reject(msg *nats.Msg, err error) {
+	responseByte, merr := sonic.Marshal(models.JobBodyContent{Details: map[string]any{"error": AsError(err)}})
+	if merr != nil {
+		baseLogger().Errorw("reject marshal error", "error", merr)
+		return
+	}
+	respond(msg, responseByte)
+}
+
+func rejectWithBody(msg *nats.Msg, body map[string]any) {
 	responseBody := models.JobBodyContent{Details: map[string]any{"error": body}}
 	responseByte, err := sonic.Marshal(responseBody)
 	if err != nil {
-		fmt.Println(err)
+		baseLogger().Errorw("reject marshal error", "error", err)
+		return
+	}
+	respond(msg, responseByte)
+}
+
+// Accept resolves the plugin owning msg.Subject and accepts the request on its behalf
+func Accept(msg *nats.Msg) (jobId string) {
+	p := GetPluginBySubject(msg.Subject)
+	if p == nil {
+		return ""
+	}
+	return p.Accept(msg)
+}
+
+// RejectWithBody replies to a request with body as details.error, without an error code.
+//
+// Deprecated: use Reject with an *Error, e.g. NewError(code, message).WithDetails(body).
+func RejectWithBody(msg *nats.Msg, body map[string]any) {
+	rejectWithBody(msg, body)
+}
+
+// Reject replies to a request with err, errors that are not an *Error are reported as internal
+func Reject(msg *nats.Msg, err error) {
+	if p := GetPluginBySubject(msg.Subject); p != nil {
+		p.Reject(msg, err)
 		return
 	}
-	msg.Respond(responseByte)
+	reject(msg, err)
 }
 
 // for multi plugin handler
@@ -52,12 +129,23 @@ func GetPluginById(pluginId string) *Plugin {
 	return nil
 }
 
+// GetPluginBySubject returns the plugin a request subject is addressed to
+func GetPluginBySubject(subject string) *Plugin {
+	if p, ok := GetPluginHolder().bySubject(subject); ok {
+		return p
+	}
+	return nil
+}
+
+// GetPlugins returns every registered plugin in registration order
+func GetPlugins() []*Plugin {
+	return GetPluginHolder().list()
+}
+
 // Get First Registered Plugin
 func GetPlugin() *Plugin {
-	h := GetPluginHolder()
-	for _, v := range h.holder {
-		return v
+	if p, ok := GetPluginHolder().first(); ok {
+		return p
 	}
 	return nil
-
 }
@@ -0,0 +1,27 @@
+package sdkv2
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestRejectCountsMetric(t *testing.T) {
+	p := &Plugin{sdk: &SorenSDK{pluginID: "reject-test", metrics: newSDKMetrics()}}
+	GetPluginHolder().add("reject-test", p)
+
+	Reject(&nats.Msg{Subject: "soren.cpu.reject-test.delete"}, NewError(CodeInvalidInput, "bad id"))
+	p.Reject(&nats.Msg{Subject: "soren.cpu.reject-test.delete"}, NewError(CodeForbidden, "not yours"))
+	req := &Request{Msg: &nats.Msg{Subject: "soren.cpu.reject-test.list"}, Method: "list", plugin: p}
+	req.Fail(NewError(CodeInternal, "broken"))
+
+	got := map[string]bool{}
+	for _, s := range p.sdk.metrics.actionsRejected.snapshot() {
+		got[s.values[0]+" "+s.values[1]] = true
+	}
+	for _, want := range []string{"delete " + string(CodeInvalidInput), "delete " + string(CodeForbidden), "list " + string(CodeInternal)} {
+		if !got[want] {
+			t.Errorf("rejection %q not counted, got %v", want, got)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package logtool
 
 import (
 	"log"
+	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -10,11 +12,14 @@ import (
 var (
 	ServiceName string
 	DevMode     bool
-	sugar       *zap.SugaredLogger
+	sugar       atomic.Pointer[zap.SugaredLogger] // swapped while other goroutines log
+	base        *zap.Logger
+	extraCores  []zapcore.Core
+	coresMutex  sync.Mutex
 )
 
 func GetLogger() *zap.SugaredLogger {
-	return sugar
+	return sugar.Load()
 }
 
 func Init(serviceName string, devMode bool) {
@@ -42,7 +47,52 @@ func Init(serviceName string, devMode bool) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	sugar = logger.Sugar()
+	setLogger(logger)
+}
+
+// AddCore tees core into the logger, e.g. to ship logs elsewhere.
+// It stays when Init or InitWithSentry run again.
+func AddCore(core zapcore.Core) {
+	coresMutex.Lock()
+	defer coresMutex.Unlock()
+	extraCores = append(extraCores, core)
+	build()
+}
+
+// RemoveCore removes a core added with AddCore
+func RemoveCore(core zapcore.Core) {
+	coresMutex.Lock()
+	defer coresMutex.Unlock()
+	for i, c := range extraCores {
+		if c == core {
+			extraCores = append(extraCores[:i:i], extraCores[i+1:]...)
+			break
+		}
+	}
+	build()
+}
+
+// setLogger makes logger the base of the logger GetLogger returns
+func setLogger(logger *zap.Logger) {
+	coresMutex.Lock()
+	defer coresMutex.Unlock()
+	base = logger
+	build()
+}
+
+// build tees the added cores into the base logger, coresMutex must be held
+func build() {
+	if base == nil {
+		return
+	}
+	if len(extraCores) == 0 {
+		sugar.Store(base.Sugar())
+		return
+	}
+	cores := extraCores
+	sugar.Store(base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+	})).Sugar())
 }
 
 // Custom Fiber logger middleware for zap
@@ -0,0 +1,44 @@
+package logtool
+
+import (
+	"sync"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAddCoreWhileLogging(t *testing.T) {
+	Init("test-service", false)
+	var wg sync.WaitGroup
+	done, started := make(chan struct{}), make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(started)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				GetLogger().Debug("logging while cores change")
+			}
+		}
+	}()
+	<-started
+	for range 1000 {
+		core, _ := observer.New(zapcore.InfoLevel)
+		AddCore(core)
+		RemoveCore(core)
+	}
+	close(done)
+	wg.Wait()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	AddCore(core)
+	defer RemoveCore(core)
+	GetLogger().Info("after")
+	if logs.Len() != 1 {
+		t.Fatalf("added core got %d entries, want 1", logs.Len())
+	}
+}
@@ -2,27 +2,64 @@ package logtool
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"time"
 
 	"github.com/getsentry/sentry-go"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func InitWithSentry(serviceName, dsn string) {
-	DevMode = false
-	ServiceName = serviceName
+// SentryOptions configures InitWithSentryOptions, zero values take the defaults
+type SentryOptions struct {
+	DSN string
+	// Environment and Release are taken from SENTRY_ENVIRONMENT and SENTRY_RELEASE when empty
+	Environment string
+	Release     string
+	// SampleRate is the share of events sent, all of them when zero
+	SampleRate float64
+	// TracesSampleRate is the share of transactions sent, all of them when zero
+	TracesSampleRate float64
+	// EventLevel is the level from which entries are sent as events, warn by default
+	EventLevel *zapcore.Level
+	// BreadcrumbLevel is the level from which lower entries are kept as breadcrumbs of the next event,
+	// debug by default
+	BreadcrumbLevel *zapcore.Level
+}
+
+// InitWithSentry logs to stdout and sends warnings and errors to Sentry
+func InitWithSentry(serviceName, dsn string) error {
+	return InitWithSentryOptions(serviceName, SentryOptions{DSN: dsn})
+}
+
+// InitWithSentryOptions logs to stdout and sends entries to Sentry as configured by opts
+func InitWithSentryOptions(serviceName string, opts SentryOptions) error {
+	if opts.SampleRate == 0 {
+		opts.SampleRate = 1.0
+	}
+	if opts.TracesSampleRate == 0 {
+		opts.TracesSampleRate = 1.0
+	}
+	eventLevel, breadcrumbLevel := zapcore.WarnLevel, zapcore.DebugLevel
+	if opts.EventLevel != nil {
+		eventLevel = *opts.EventLevel
+	}
+	if opts.BreadcrumbLevel != nil {
+		breadcrumbLevel = *opts.BreadcrumbLevel
+	}
 	err := sentry.Init(sentry.ClientOptions{
-		Dsn:              dsn,
-		TracesSampleRate: 1.0,
+		Dsn:              opts.DSN,
+		Environment:      opts.Environment,
+		Release:          opts.Release,
+		SampleRate:       opts.SampleRate,
+		TracesSampleRate: opts.TracesSampleRate,
 		AttachStacktrace: true,
 	})
 	if err != nil {
-		log.Fatalf("sentry.Init: %s", err)
+		return fmt.Errorf("sentry.Init: %w", err)
 	}
+	DevMode = false
+	ServiceName = serviceName
 
 	zapConfig := zap.NewProductionConfig()
 	zapConfig.EncoderConfig.TimeKey = "time"
@@ -34,7 +71,7 @@ func InitWithSentry(serviceName, dsn string) {
 		zapcore.InfoLevel,
 	)
 
-	sentryCore := newSentryCore(zapcore.WarnLevel)
+	sentryCore := newSentryCore(sentry.CurrentHub(), eventLevel, breadcrumbLevel)
 
 	core := zapcore.NewTee(
 		consoleCore,
@@ -42,21 +79,31 @@ func InitWithSentry(serviceName, dsn string) {
 	)
 
 	logger := zap.New(core, zap.AddCaller())
-	sugar = logger.Sugar()
+	setLogger(logger)
+	return nil
 }
 
+// sentryCore sends entries from eventLevel to Sentry as events,
+// and keeps lower ones from breadcrumbLevel as breadcrumbs of the next event
 type sentryCore struct {
 	zapcore.LevelEnabler
+	hub        *sentry.Hub
+	eventLevel zapcore.Level
+	fields     []zapcore.Field
 }
 
-func newSentryCore(enab zapcore.LevelEnabler) zapcore.Core {
+func newSentryCore(hub *sentry.Hub, eventLevel, breadcrumbLevel zapcore.Level) zapcore.Core {
 	return &sentryCore{
-		LevelEnabler: enab,
+		LevelEnabler: breadcrumbLevel,
+		hub:          hub,
+		eventLevel:   eventLevel,
 	}
 }
 
 func (c *sentryCore) With(fields []zapcore.Field) zapcore.Core {
-	return c
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
 }
 
 func (c *sentryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
@@ -67,24 +114,44 @@ func (c *sentryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore
 }
 
 func (c *sentryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
-	tags := make(map[string]string)
-	extras := make(map[string]interface{})
-
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
 	for _, field := range fields {
-		switch field.Type {
-		case zapcore.StringType:
-			tags[field.Key] = field.String
-		default:
-			extras[field.Key] = field.Interface
+		field.AddTo(enc)
+	}
+
+	if ent.Level < c.eventLevel {
+		c.hub.AddBreadcrumb(&sentry.Breadcrumb{
+			Type:      "default",
+			Category:  breadcrumbCategory(ent),
+			Message:   ent.Message,
+			Data:      enc.Fields,
+			Level:     zapLevelToSentryLevel(ent.Level),
+			Timestamp: ent.Time,
+		}, nil)
+		return nil
+	}
+
+	tags := map[string]string{"service": ServiceName}
+	extras := make(map[string]interface{})
+	for key, value := range enc.Fields {
+		// short strings are searchable tags, everything else is extra data
+		if s, ok := value.(string); ok && len(s) <= 200 {
+			tags[key] = s
+			continue
 		}
+		extras[key] = value
 	}
 
 	event := sentry.NewEvent()
 	event.Level = zapLevelToSentryLevel(ent.Level)
-
-	uniqueID := uuid.New().String()
-	event.Message = fmt.Sprintf("[%s] - %s - %s", uniqueID, ServiceName, ent.Message)
+	// the message stays as logged so that occurrences group together
+	event.Message = ent.Message
+	event.Fingerprint = []string{ServiceName, ent.LoggerName, ent.Message}
 	event.Timestamp = ent.Time
+	event.Logger = ent.LoggerName
 
 	extras["logger"] = "zap"
 	extras["level"] = ent.Level.String()
@@ -96,12 +163,8 @@ func (c *sentryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
 		extras["caller"] = callerStr
 	}
 
-	if len(tags) > 0 {
-		event.Tags = tags
-	}
-	if len(extras) > 0 {
-		event.Extra = extras
-	}
+	event.Tags = tags
+	event.Extra = extras
 
 	if ent.Level >= zapcore.ErrorLevel {
 		event.Threads = []sentry.Thread{{
@@ -111,15 +174,23 @@ func (c *sentryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
 		}}
 	}
 
-	sentry.CaptureEvent(event)
+	c.hub.CaptureEvent(event)
 	return nil
 }
 
 func (c *sentryCore) Sync() error {
-	sentry.Flush(2 * time.Second)
+	c.hub.Flush(2 * time.Second)
 	return nil
 }
 
+// breadcrumbCategory is the logger name of an entry, "log" for the root logger
+func breadcrumbCategory(ent zapcore.Entry) string {
+	if ent.LoggerName != "" {
+		return ent.LoggerName
+	}
+	return "log"
+}
+
 func zapLevelToSentryLevel(level zapcore.Level) sentry.Level {
 	switch level {
 	case zapcore.DebugLevel:
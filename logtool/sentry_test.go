@@ -0,0 +1,66 @@
+package logtool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// captureTransport keeps the events a Sentry client sends
+type captureTransport struct {
+	mutex  sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *captureTransport) Flush(time.Duration) bool              { return true }
+func (t *captureTransport) FlushWithContext(context.Context) bool { return true }
+func (t *captureTransport) Configure(sentry.ClientOptions)        {}
+func (t *captureTransport) Close()                                {}
+func (t *captureTransport) SendEvent(event *sentry.Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.events = append(t.events, event)
+}
+
+func TestSentryCore(t *testing.T) {
+	transport := &captureTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: "https://key@sentry.invalid/1", Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := sentry.NewHub(client, sentry.NewScope())
+	ServiceName = "test-service"
+	logger := zap.New(newSentryCore(hub, zapcore.WarnLevel, zapcore.DebugLevel)).With(zap.String("jobId", "job-1"))
+
+	logger.Debug("cloning", zap.String("repo", "sdk"))
+	logger.Warn("disk almost full", zap.Int("free", 3))
+	logger.Warn("disk almost full", zap.Int("free", 1))
+
+	if len(transport.events) != 2 {
+		t.Fatalf("got %d events, want 2", len(transport.events))
+	}
+	first, second := transport.events[0], transport.events[1]
+	if first.Message != "disk almost full" || second.Message != first.Message {
+		t.Errorf("messages %q and %q are not stable", first.Message, second.Message)
+	}
+	if len(first.Fingerprint) == 0 || first.Fingerprint[len(first.Fingerprint)-1] != "disk almost full" {
+		t.Errorf("fingerprint %v", first.Fingerprint)
+	}
+	if first.Tags["jobId"] != "job-1" || first.Tags["service"] != "test-service" {
+		t.Errorf("tags %v miss the With fields", first.Tags)
+	}
+	if first.Extra["free"] != int64(3) {
+		t.Errorf("extra %v", first.Extra)
+	}
+	if first.Level != sentry.LevelWarning {
+		t.Errorf("level %s", first.Level)
+	}
+	if len(first.Breadcrumbs) != 1 || first.Breadcrumbs[0].Message != "cloning" || first.Breadcrumbs[0].Data["repo"] != "sdk" {
+		t.Errorf("breadcrumbs %+v", first.Breadcrumbs)
+	}
+}
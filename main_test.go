@@ -78,12 +78,12 @@ func TestMain(t *testing.T) {
 		Title:  "Clone/Pull Repo",
 		Form: models.ActionFormBuilder{
 			Jsonui:     map[string]any{"type": "Control", "scope": "#/properties/project"},
-			Jsonschema: map[string]any{"properties": map[string]any{"project": map[string]any{"enum": makeEnumsProject()}}},
+			Jsonschema: map[string]any{"type": "object", "properties": map[string]any{"project": map[string]any{"type": "string", "enum": makeEnumsProject()}}},
 		},
 		RequestHandler: func(msg *nats.Msg)  {
 			// data:=msg.Data
 			// for example in this step we register a job in local database or external system - mae a scan in Joern
-			sdkv2.RejectWithBody(msg,map[string]any{"reason":"rate limit exceeded"})
+			sdkv2.Reject(msg, sdkv2.NewError(sdkv2.CodeRateLimited, "rate limit exceeded"))
 		},
 	
 	}, models.Action{
@@ -91,20 +91,21 @@ func TestMain(t *testing.T) {
 		Title:  "Scan Code And Create Graph",
 		Form: models.ActionFormBuilder{
 			Jsonui:     map[string]any{"type": "Control", "scope": "#/properties/reponame"},
-			Jsonschema: map[string]any{"properties": map[string]any{"reponame": map[string]any{"type": "string"}}},
+			Jsonschema: map[string]any{"type": "object", "properties": map[string]any{"reponame": map[string]any{"type": "string"}}},
 		},
-		RequestHandler: func(msg *nats.Msg)  {
-		jobId:=sdkv2.Accept(msg)
+	},
+	})
+	plugin.Handle("scan", func(req *sdkv2.Request) {
+		req.Accept()
+		job := req.Job()
 		//
-		sdkv2.GetPlugin().Progress(jobId,models.ProgressCommand,models.JobProgress{Progress: 10})
-		sdkv2.GetPlugin().Progress(jobId,models.ProgressCommand,models.JobProgress{Progress: 20})
-		sdkv2.GetPlugin().Progress(jobId,models.ProgressCommand,models.JobProgress{Progress: 30})
-		sdkv2.GetPlugin().Progress(jobId,models.ProgressCommand,models.JobProgress{Progress: 40})
+		job.Progress(models.JobProgress{Progress: 10})
+		job.Progress(models.JobProgress{Progress: 20})
+		job.Progress(models.JobProgress{Progress: 30})
+		job.Progress(models.JobProgress{Progress: 40})
 
 		//
-		sdkv2.GetPlugin().Done(jobId,map[string]any{"details":"final result ....."})
-		},
-	},
+		job.Done(map[string]any{"details": "final result ....."})
 	})
 	event := sdkv2.NewEventLogger(sdkInstance)
 	event.Log("remote-mate-pc", models.LogLevelInfo, "start plugin", nil)
@@ -112,15 +113,15 @@ func TestMain(t *testing.T) {
 	select {}
 }
 
-func settingsUpdateHandler(msg *nats.Msg) any {
-	fmt.Println("New Update As Settings : ", string(msg.Data))
+func settingsUpdateHandler(req *sdkv2.Request) any {
+	fmt.Println("New Update As Settings : ", string(req.Msg.Data))
 	settings:=map[string]any{}
-	err:=sonic.Unmarshal(msg.Data,&settings)
+	err:=sonic.Unmarshal(req.Msg.Data,&settings)
 	if err!=nil{
 		fmt.Println("Error Unmarshalling Settings:",err)
 		return map[string]any{"status": "error"}
 	}
-	err=os.WriteFile("my_database.json",msg.Data,0644)
+	err=os.WriteFile("my_database.json",req.Msg.Data,0644)
 	if err!=nil{
 		fmt.Println("Error Writing Settings to File:", err)
 			return map[string]any{"status": "not_accepted", "error": err.Error()}